@@ -0,0 +1,15 @@
+data "external_starlark" "example" {
+  script = <<-EOT
+    def main(query):
+        tags = json.decode(query["tags"])
+        return {
+            "name": "%s-%s" % (query["service"], tags["environment"]),
+            "owner": tags.get("owner", "unknown"),
+        }
+  EOT
+
+  query = {
+    service = "inventory"
+    tags    = jsonencode({ environment = "production", owner = "platform" })
+  }
+}
@@ -0,0 +1,18 @@
+provider "external" {
+  # Refuse to execute programs which are only found relative to the
+  # current directory.
+  strict_program_lookup = true
+
+  # Programs shared by many configurations can be declared once and
+  # referenced by name with the "profile" attribute of the data source.
+  profiles = {
+    inventory = {
+      program = ["/opt/tf-helpers/inventory", "--format=json"]
+      timeout = "30s"
+
+      query = {
+        region = "eu-west-1"
+      }
+    }
+  }
+}
@@ -0,0 +1,290 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// serviceName is the name of the gRPC service of helpers. Its messages are
+// google.protobuf.Struct values containing the JSON encoding of the request
+// and response types below, so no generated code is required.
+const serviceName = "terraform.external.plugin.v1.Helper"
+
+type readRequest struct {
+	Query map[string]string `json:"query"`
+}
+
+type readResponse struct {
+	Result map[string]string `json:"result"`
+	Error  *Error            `json:"error,omitempty"`
+}
+
+type validateRequest struct {
+	Query map[string]string `json:"query"`
+}
+
+type validateResponse struct {
+	Error *Error `json:"error,omitempty"`
+}
+
+type schemaRequest struct{}
+
+type schemaResponse struct {
+	Schema *Schema `json:"schema,omitempty"`
+	Error  *Error  `json:"error,omitempty"`
+}
+
+// HelperPlugin is the go-plugin implementation of helpers. It only supports
+// gRPC.
+type HelperPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+
+	// Impl is the helper served by helper programs. It is nil in clients.
+	Impl Helper
+}
+
+var _ plugin.GRPCPlugin = (*HelperPlugin)(nil)
+
+func (p *HelperPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&serviceDesc, &server{impl: p.Impl})
+	return nil
+}
+
+func (p *HelperPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &Client{conn: conn}, nil
+}
+
+// helperServer is the handler type of the service description.
+type helperServer interface {
+	read(ctx context.Context, req *readRequest) *readResponse
+	validate(ctx context.Context, req *validateRequest) *validateResponse
+	schema(ctx context.Context, req *schemaRequest) *schemaResponse
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*helperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Read",
+			Handler: unaryHandler("Read", func(ctx context.Context, srv helperServer, req *readRequest) interface{} {
+				return srv.read(ctx, req)
+			}),
+		},
+		{
+			MethodName: "Validate",
+			Handler: unaryHandler("Validate", func(ctx context.Context, srv helperServer, req *validateRequest) interface{} {
+				return srv.validate(ctx, req)
+			}),
+		},
+		{
+			MethodName: "Schema",
+			Handler: unaryHandler("Schema", func(ctx context.Context, srv helperServer, req *schemaRequest) interface{} {
+				return srv.schema(ctx, req)
+			}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "externalplugin",
+}
+
+// unaryHandler returns the gRPC handler of a method, which decodes the
+// request from a Struct and encodes the response into one.
+func unaryHandler[Req any](method string, handle func(context.Context, helperServer, *Req) interface{}) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(structpb.Struct)
+
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+
+		call := func(ctx context.Context, in interface{}) (interface{}, error) {
+			req := new(Req)
+
+			if err := fromStruct(in.(*structpb.Struct), req); err != nil {
+				return nil, err
+			}
+
+			return toStruct(handle(ctx, srv.(helperServer), req))
+		}
+
+		if interceptor == nil {
+			return call(ctx, in)
+		}
+
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + serviceName + "/" + method,
+		}
+
+		return interceptor(ctx, in, info, call)
+	}
+}
+
+// server serves a helper.
+type server struct {
+	impl Helper
+}
+
+func (s *server) read(ctx context.Context, req *readRequest) *readResponse {
+	result, err := s.impl.Read(ctx, req.Query)
+	if err != nil {
+		return &readResponse{Error: asError(err)}
+	}
+
+	if result == nil {
+		result = map[string]string{}
+	}
+
+	return &readResponse{Result: result}
+}
+
+func (s *server) validate(ctx context.Context, req *validateRequest) *validateResponse {
+	validator, ok := s.impl.(Validator)
+	if !ok {
+		return &validateResponse{}
+	}
+
+	if err := validator.Validate(ctx, req.Query); err != nil {
+		return &validateResponse{Error: asError(err)}
+	}
+
+	return &validateResponse{}
+}
+
+func (s *server) schema(ctx context.Context, _ *schemaRequest) *schemaResponse {
+	schemaProvider, ok := s.impl.(SchemaProvider)
+	if !ok {
+		return &schemaResponse{}
+	}
+
+	schema, err := schemaProvider.Schema(ctx)
+	if err != nil {
+		return &schemaResponse{Error: asError(err)}
+	}
+
+	return &schemaResponse{Schema: schema}
+}
+
+// Client is the client of a helper, as dispensed by go-plugin. Errors
+// returned by the helper are always of type *Error, while other errors are
+// caused by the transport.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+var (
+	_ Helper         = (*Client)(nil)
+	_ Validator      = (*Client)(nil)
+	_ SchemaProvider = (*Client)(nil)
+)
+
+func (c *Client) Read(ctx context.Context, query map[string]string) (map[string]string, error) {
+	var resp readResponse
+
+	if err := c.invoke(ctx, "Read", &readRequest{Query: query}, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	return resp.Result, nil
+}
+
+// Validate validates the query. It succeeds if the helper does not
+// implement Validator.
+func (c *Client) Validate(ctx context.Context, query map[string]string) error {
+	var resp validateResponse
+
+	if err := c.invoke(ctx, "Validate", &validateRequest{Query: query}, &resp); err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
+
+// Schema returns the schema of the helper, which is nil if the helper does
+// not implement SchemaProvider.
+func (c *Client) Schema(ctx context.Context) (*Schema, error) {
+	var resp schemaResponse
+
+	if err := c.invoke(ctx, "Schema", &schemaRequest{}, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	return resp.Schema, nil
+}
+
+func (c *Client) invoke(ctx context.Context, method string, req interface{}, resp interface{}) error {
+	in, err := toStruct(req)
+	if err != nil {
+		return err
+	}
+
+	out := new(structpb.Struct)
+
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, in, out); err != nil {
+		return err
+	}
+
+	return fromStruct(out, resp)
+}
+
+// asError converts an error returned by a helper into an *Error.
+func asError(err error) *Error {
+	var e *Error
+
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return &Error{Detail: err.Error()}
+}
+
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode message: %w", err)
+	}
+
+	s := new(structpb.Struct)
+
+	if err := protojson.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("unable to encode message: %w", err)
+	}
+
+	return s, nil
+}
+
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	data, err := protojson.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("unable to decode message: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unable to decode message: %w", err)
+	}
+
+	return nil
+}
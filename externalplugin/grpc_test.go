@@ -0,0 +1,137 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalplugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-plugin"
+)
+
+type testHelper struct{}
+
+func (testHelper) Read(_ context.Context, query map[string]string) (map[string]string, error) {
+	if _, ok := query["fail"]; ok {
+		return nil, errors.New("I was asked to fail")
+	}
+
+	return map[string]string{"value": query["value"]}, nil
+}
+
+func (testHelper) Validate(_ context.Context, query map[string]string) error {
+	if query["value"] == "invalid" {
+		return &Error{Summary: "Invalid Value", Detail: "The value is invalid.", QueryKey: "value"}
+	}
+
+	return nil
+}
+
+func (testHelper) Schema(_ context.Context) (*Schema, error) {
+	return &Schema{
+		Description: "Echoes the value.",
+		Query: map[string]QueryKey{
+			"value": {Description: "The value to echo.", Required: true},
+			"fail":  {},
+		},
+	}, nil
+}
+
+type readOnlyHelper struct{}
+
+func (readOnlyHelper) Read(_ context.Context, _ map[string]string) (map[string]string, error) {
+	return nil, nil
+}
+
+func testClient(t *testing.T, impl Helper) *Client {
+	t.Helper()
+
+	client, _ := plugin.TestPluginGRPCConn(t, false, PluginMap(impl))
+	t.Cleanup(func() { _ = client.Close() })
+
+	raw, err := client.Dispense(PluginName)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return raw.(*Client)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := testClient(t, testHelper{})
+
+	result, err := client.Read(ctx, map[string]string{"value": "pizza"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if diff := cmp.Diff(map[string]string{"value": "pizza"}, result); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	_, err = client.Read(ctx, map[string]string{"fail": "true"})
+	if diff := cmp.Diff(&Error{Detail: "I was asked to fail"}, err); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	err = client.Validate(ctx, map[string]string{"value": "invalid"})
+	if diff := cmp.Diff(&Error{Summary: "Invalid Value", Detail: "The value is invalid.", QueryKey: "value"}, err); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	if err := client.Validate(ctx, map[string]string{"value": "pizza"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedSchema := &Schema{
+		Description: "Echoes the value.",
+		Query: map[string]QueryKey{
+			"value": {Description: "The value to echo.", Required: true},
+			"fail":  {},
+		},
+	}
+
+	if diff := cmp.Diff(expectedSchema, schema); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+}
+
+func TestClient_OptionalInterfaces(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := testClient(t, readOnlyHelper{})
+
+	result, err := client.Read(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if diff := cmp.Diff(map[string]string{}, result); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	if err := client.Validate(ctx, nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if schema != nil {
+		t.Errorf("expected no schema, got: %v", schema)
+	}
+}
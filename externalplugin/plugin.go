@@ -0,0 +1,111 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package externalplugin implements helpers which are executed by the plugin
+// mode of the external data source, as an alternative to the protocol of
+// external programs.
+//
+// A helper is a Go program which calls Serve with an implementation of
+// Helper. The provider starts the program once, completes a handshake with
+// it and then sends every read to the running program over gRPC:
+//
+//	func main() {
+//		externalplugin.Serve(&inventoryHelper{})
+//	}
+//
+// Helpers may additionally implement Validator and SchemaProvider, and may
+// return an *Error to control the diagnostic reported by Terraform.
+package externalplugin
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// Handshake is the handshake configuration shared by the provider and
+// helpers. Programs which are started without the magic cookie, such as by a
+// user, exit with an explanatory message instead of waiting for a provider.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "TF_EXTERNAL_PLUGIN_MAGIC_COOKIE",
+	MagicCookieValue: "4d9c2b0e6a8f4c1f9e7d3b5a2c6e8f10",
+}
+
+// PluginName is the name under which helpers are served.
+const PluginName = "helper"
+
+// Helper is the interface implemented by helpers.
+type Helper interface {
+	// Read returns the result of the data source for the query.
+	Read(ctx context.Context, query map[string]string) (map[string]string, error)
+}
+
+// Validator is optionally implemented by helpers to validate the query
+// before it is read.
+type Validator interface {
+	Validate(ctx context.Context, query map[string]string) error
+}
+
+// SchemaProvider is optionally implemented by helpers to declare the query
+// keys they support, which are then verified by the provider before the
+// query is validated and read.
+type SchemaProvider interface {
+	Schema(ctx context.Context) (*Schema, error)
+}
+
+// Schema describes the query supported by a helper.
+type Schema struct {
+	// Description is a human readable description of the helper.
+	Description string `json:"description,omitempty"`
+
+	// Query contains the supported query keys. Queries with other keys are
+	// rejected.
+	Query map[string]QueryKey `json:"query"`
+}
+
+// QueryKey describes a single key of the query.
+type QueryKey struct {
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Error is an error returned by a helper which is reported by the provider
+// as a diagnostic with the given summary and detail. Errors other than
+// *Error are reported with a generic summary.
+type Error struct {
+	// Summary is the short summary of the diagnostic.
+	Summary string `json:"summary,omitempty"`
+
+	// Detail is the detailed explanation of the diagnostic.
+	Detail string `json:"detail"`
+
+	// QueryKey is the key of the query the error relates to, if any.
+	QueryKey string `json:"query_key,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Summary == "" {
+		return e.Detail
+	}
+
+	return e.Summary + ": " + e.Detail
+}
+
+// PluginMap returns the plugins served by helpers, with impl as the helper.
+// Clients pass a nil helper.
+func PluginMap(impl Helper) map[string]plugin.Plugin {
+	return map[string]plugin.Plugin{
+		PluginName: &HelperPlugin{Impl: impl},
+	}
+}
+
+// Serve serves the helper. It must be called from the main function of the
+// helper program and does not return until the provider stops the helper.
+func Serve(impl Helper) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         PluginMap(impl),
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
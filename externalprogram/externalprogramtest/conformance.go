@@ -0,0 +1,372 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package externalprogramtest verifies that external programs implement the
+// protocol of the external data source, so program authors find protocol
+// violations in their own tests rather than during a Terraform plan.
+//
+// Run executes the program for a battery of scenarios with the protocol
+// package, which implements the execution of programs for the provider, and
+// reports how Terraform would react to each one:
+//
+//	func TestConformance(t *testing.T) {
+//		externalprogramtest.Run(t, externalprogramtest.Config{
+//			Program:    []string{"./lookup.py"},
+//			Query:      map[string]string{"name": "example"},
+//			ErrorQuery: map[string]string{"name": ""},
+//		})
+//	}
+package externalprogramtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-external/externalprogram/protocol"
+)
+
+// Reaction is how the read of the data source reacts to the execution of a
+// program.
+type Reaction string
+
+const (
+	// ReactionResult is a successful read, which sets the result of the data
+	// source.
+	ReactionResult Reaction = "result"
+
+	// ReactionError is a failed execution, where the program exited with a
+	// non-zero status and an error message on the standard error, which is
+	// included in the diagnostic.
+	ReactionError Reaction = "error"
+
+	// ReactionErrorWithoutMessage is a failed execution, where the program
+	// exited with a non-zero status without an error message, so the
+	// diagnostic does not explain the failure.
+	ReactionErrorWithoutMessage Reaction = "error without message"
+
+	// ReactionInvalidResult is a successful execution, whose output is not a
+	// JSON object with string values, such as invalid JSON.
+	ReactionInvalidResult Reaction = "invalid result"
+
+	// ReactionTimeout is an execution terminated after the timeout.
+	ReactionTimeout Reaction = "timeout"
+
+	// ReactionOther is any other failure, such as a program which cannot be
+	// found.
+	ReactionOther Reaction = "other"
+)
+
+// DefaultTimeout is the timeout of each scenario, unless configured.
+const DefaultTimeout = "30s"
+
+// LargeValueSize is the size in bytes of the value of the large values
+// scenario.
+const LargeValueSize = 1 << 20
+
+// Config describes the program under test.
+type Config struct {
+	// Program is the program and its arguments, as in the program attribute
+	// of the data source.
+	Program []string
+
+	// WorkingDir is the working directory of the program, if not empty.
+	WorkingDir string
+
+	// Timeout is the timeout of each scenario, such as 30s. DefaultTimeout
+	// is used if empty.
+	Timeout string
+
+	// Query is included in the query of every scenario, such as the keys
+	// required by the program. The empty query scenario only sends these
+	// keys.
+	Query map[string]string
+
+	// ErrorQuery is a query for which the program must fail, by printing an
+	// error message to the standard error and exiting with a non-zero status.
+	// The error scenario is skipped if nil.
+	ErrorQuery map[string]string
+
+	// Skip contains the names of default scenarios which do not apply to
+	// the program, such as unicode keys for programs rejecting unknown keys.
+	Skip []string
+
+	// Scenarios are additional scenarios.
+	Scenarios []Scenario
+}
+
+// Scenario is a query sent to the program along with the reaction expected
+// of a conforming program.
+type Scenario struct {
+	Name        string
+	Description string
+
+	// Query is the query of the data source, which is merged on top of the
+	// query of the configuration. Null values are represented by nil, and
+	// are never delivered to the program.
+	Query map[string]*string
+
+	// Input, if not nil, is written to the standard input of the program
+	// instead of the query, for input the provider never sends.
+	Input []byte
+
+	// Expected is the reaction expected of a conforming program.
+	Expected Reaction
+}
+
+// Outcome is the reaction of the data source to a scenario.
+type Outcome struct {
+	Scenario Scenario
+	Reaction Reaction
+
+	// Result is the result of the data source if the reaction is
+	// ReactionResult.
+	Result map[string]string
+
+	// Stderr is the standard error of the program.
+	Stderr string
+
+	// Err is the error of the execution or of the decoding of the result,
+	// unless the reaction is ReactionResult.
+	Err error
+}
+
+// Conforms returns whether the reaction is the expected reaction.
+func (o Outcome) Conforms() bool {
+	return o.Reaction == o.Scenario.Expected
+}
+
+func (o Outcome) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "scenario %q: expected %s, got %s", o.Scenario.Name, o.Scenario.Expected, o.Reaction)
+
+	if o.Reaction == ReactionResult {
+		fmt.Fprintf(&b, " with %d result keys", len(o.Result))
+	}
+
+	if o.Err != nil {
+		fmt.Fprintf(&b, "\n\nError: %s", o.Err)
+	}
+
+	if o.Stderr != "" {
+		fmt.Fprintf(&b, "\nStderr: %s", o.Stderr)
+	}
+
+	return b.String()
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+// DefaultScenarios returns the scenarios every program is verified with.
+func DefaultScenarios() []Scenario {
+	return []Scenario{
+		{
+			Name:        "empty query",
+			Description: "A query without keys, other than those of the configuration.",
+			Query:       map[string]*string{},
+			Expected:    ReactionResult,
+		},
+		{
+			Name:        "unicode keys",
+			Description: "Keys and values outside of ASCII, which must be decoded as UTF-8.",
+			Query: map[string]*string{
+				"ключ":              stringPointer("значение"),
+				"キー":                stringPointer("値"),
+				"emoji_🎉":           stringPointer("✓"),
+				"combining_e\u0301": stringPointer("e\u0301"),
+			},
+			Expected: ReactionResult,
+		},
+		{
+			Name:        "large values",
+			Description: fmt.Sprintf("A value of %d bytes, which must be read completely.", LargeValueSize),
+			Query: map[string]*string{
+				"large": stringPointer(strings.Repeat("x", LargeValueSize)),
+			},
+			Expected: ReactionResult,
+		},
+		{
+			Name:        "null values",
+			Description: "Null values of the data source, whose keys are omitted from the query of the program.",
+			Query: map[string]*string{
+				"null": nil,
+			},
+			Expected: ReactionResult,
+		},
+		{
+			Name:        "json output",
+			Description: "Values resembling numbers, booleans and JSON, which must still be written as a JSON object with string values.",
+			Query: map[string]*string{
+				"number":  stringPointer("8080"),
+				"boolean": stringPointer("true"),
+				"json":    stringPointer(`{"key":["value"]}`),
+			},
+			Expected: ReactionResult,
+		},
+		{
+			Name:        "invalid query",
+			Description: "A standard input which is not a JSON object, which the program must reject by printing an error message to the standard error and exiting with a non-zero status.",
+			Input:       []byte("not json"),
+			Expected:    ReactionError,
+		},
+	}
+}
+
+// Check runs every scenario of the configuration and returns their outcomes.
+func Check(ctx context.Context, config Config) []Outcome {
+	skipped := make(map[string]bool, len(config.Skip))
+
+	for _, name := range config.Skip {
+		skipped[name] = true
+	}
+
+	var scenarios []Scenario
+
+	for _, scenario := range DefaultScenarios() {
+		if !skipped[scenario.Name] {
+			scenarios = append(scenarios, scenario)
+		}
+	}
+
+	if config.ErrorQuery != nil {
+		query := make(map[string]*string, len(config.ErrorQuery))
+
+		for key, value := range config.ErrorQuery {
+			query[key] = stringPointer(value)
+		}
+
+		scenarios = append(scenarios, Scenario{
+			Name:        "error",
+			Description: "A query for which the program must print an error message to the standard error and exit with a non-zero status.",
+			Query:       query,
+			Expected:    ReactionError,
+		})
+	}
+
+	scenarios = append(scenarios, config.Scenarios...)
+
+	outcomes := make([]Outcome, 0, len(scenarios))
+
+	for _, scenario := range scenarios {
+		outcomes = append(outcomes, checkScenario(ctx, config, scenario))
+	}
+
+	return outcomes
+}
+
+// Run runs every scenario of the configuration as a subtest, which fails if
+// the reaction of the data source is not the expected reaction.
+func Run(t *testing.T, config Config) {
+	t.Helper()
+
+	for _, outcome := range Check(context.Background(), config) {
+		t.Run(outcome.Scenario.Name, func(t *testing.T) {
+			if !outcome.Conforms() {
+				t.Errorf("%s", outcome)
+				return
+			}
+
+			t.Logf("%s", outcome)
+		})
+	}
+}
+
+// checkScenario executes the program for a single scenario and classifies
+// its execution as the provider does.
+func checkScenario(ctx context.Context, config Config, scenario Scenario) Outcome {
+	outcome := Outcome{
+		Scenario: scenario,
+		Reaction: ReactionOther,
+	}
+
+	input := scenario.Input
+
+	if input == nil {
+		query := make(map[string]string, len(config.Query)+len(scenario.Query))
+
+		for key, value := range config.Query {
+			query[key] = value
+		}
+
+		// Null values are omitted from the query, as by the data source.
+		for key, value := range scenario.Query {
+			if value == nil {
+				delete(query, key)
+				continue
+			}
+
+			query[key] = *value
+		}
+
+		encoded, err := protocol.EncodeQuery(query)
+		if err != nil {
+			outcome.Err = err
+			return outcome
+		}
+
+		input = encoded
+	}
+
+	timeout := config.Timeout
+
+	if timeout == "" {
+		timeout = DefaultTimeout
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		outcome.Err = fmt.Errorf("invalid timeout: %w", err)
+		return outcome
+	}
+
+	if len(config.Program) == 0 {
+		outcome.Err = errors.New("program is required")
+		return outcome
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, config.Program[0], config.Program[1:]...)
+	cmd.Dir = config.WorkingDir
+
+	output, stderr, err := protocol.Execute(ctx, cmd, input)
+
+	outcome.Stderr = stderr
+
+	if err != nil {
+		outcome.Err = err
+
+		var exitErr *protocol.ExitError
+
+		switch {
+		case errors.Is(err, protocol.ErrTimeout):
+			outcome.Reaction = ReactionTimeout
+		case errors.As(err, &exitErr) && exitErr.HasMessage():
+			outcome.Reaction = ReactionError
+		case errors.As(err, &exitErr):
+			outcome.Reaction = ReactionErrorWithoutMessage
+		}
+
+		return outcome
+	}
+
+	result, err := protocol.DecodeResult(output)
+	if err != nil {
+		outcome.Reaction = ReactionInvalidResult
+		outcome.Err = err
+		return outcome
+	}
+
+	outcome.Reaction = ReactionResult
+	outcome.Result = result
+
+	return outcome
+}
@@ -0,0 +1,268 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalprogramtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// programMode is the argument running the test binary as an external program
+// with the behavior of the following argument.
+const programMode = "-external-program"
+
+func TestMain(m *testing.M) {
+	if len(os.Args) == 3 && os.Args[1] == programMode {
+		os.Exit(runProgram(os.Args[2]))
+	}
+
+	os.Exit(m.Run())
+}
+
+// runProgram implements the programs under test, returning the exit status.
+func runProgram(behavior string) int {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading query: %s\n", err)
+		return 1
+	}
+
+	var query map[string]string
+
+	if err := json.Unmarshal(input, &query); err != nil {
+		fmt.Fprintf(os.Stderr, "decoding query: %s\n", err)
+		return 1
+	}
+
+	switch behavior {
+	case "echo":
+		if query["name"] == "" {
+			fmt.Fprintf(os.Stderr, "name is required\n")
+			return 1
+		}
+	case "silent":
+		if query["name"] == "" {
+			return 1
+		}
+	case "invalid":
+		fmt.Fprintf(os.Stdout, "not json")
+		return 0
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	case "typed":
+		result := make(map[string]interface{}, len(query))
+
+		for key, value := range query {
+			result[key] = value
+
+			if number, err := strconv.Atoi(value); err == nil {
+				result[key] = number
+			}
+		}
+
+		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "encoding result: %s\n", err)
+			return 1
+		}
+
+		return 0
+	case "truncate":
+		for key, value := range query {
+			if len(value) > 1024 {
+				query[key] = value[:1024]
+			}
+		}
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(query); err != nil {
+		fmt.Fprintf(os.Stderr, "encoding result: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+func testConfig(behavior string) Config {
+	return Config{
+		Program:    []string{os.Args[0], programMode, behavior},
+		Query:      map[string]string{"name": "example"},
+		ErrorQuery: map[string]string{"name": ""},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		config   Config
+		expected map[string]Reaction
+	}{
+		"conforming": {
+			config: testConfig("echo"),
+			expected: map[string]Reaction{
+				"empty query":   ReactionResult,
+				"unicode keys":  ReactionResult,
+				"large values":  ReactionResult,
+				"null values":   ReactionResult,
+				"json output":   ReactionResult,
+				"invalid query": ReactionError,
+				"error":         ReactionError,
+			},
+		},
+		"error without message": {
+			config: testConfig("silent"),
+			expected: map[string]Reaction{
+				"empty query":   ReactionResult,
+				"unicode keys":  ReactionResult,
+				"large values":  ReactionResult,
+				"null values":   ReactionResult,
+				"json output":   ReactionResult,
+				"invalid query": ReactionError,
+				"error":         ReactionErrorWithoutMessage,
+			},
+		},
+		"invalid result": {
+			config: func() Config {
+				config := testConfig("invalid")
+				config.Skip = []string{"unicode keys", "large values", "null values", "json output"}
+				return config
+			}(),
+			expected: map[string]Reaction{
+				"empty query":   ReactionInvalidResult,
+				"invalid query": ReactionError,
+				"error":         ReactionInvalidResult,
+			},
+		},
+		"non-string values": {
+			config: func() Config {
+				config := testConfig("typed")
+				config.Skip = []string{"unicode keys", "large values", "null values", "invalid query"}
+				config.ErrorQuery = nil
+				return config
+			}(),
+			expected: map[string]Reaction{
+				"empty query": ReactionResult,
+				"json output": ReactionInvalidResult,
+			},
+		},
+		"timeout": {
+			config: func() Config {
+				config := testConfig("sleep")
+				config.Timeout = "100ms"
+				config.Skip = []string{"unicode keys", "large values", "null values", "json output", "invalid query"}
+				config.ErrorQuery = nil
+				return config
+			}(),
+			expected: map[string]Reaction{
+				"empty query": ReactionTimeout,
+			},
+		},
+		"missing program": {
+			config: Config{
+				Program: []string{"tf-acc-external-missing-program"},
+				Skip:    []string{"unicode keys", "large values", "null values", "json output", "invalid query"},
+			},
+			expected: map[string]Reaction{
+				"empty query": ReactionOther,
+			},
+		},
+		"additional scenario": {
+			config: func() Config {
+				config := testConfig("echo")
+				config.Skip = []string{"empty query", "unicode keys", "large values", "null values", "json output", "invalid query"}
+				config.ErrorQuery = nil
+				config.Scenarios = []Scenario{
+					{
+						Name:     "missing name",
+						Query:    map[string]*string{"name": nil},
+						Expected: ReactionError,
+					},
+				}
+				return config
+			}(),
+			expected: map[string]Reaction{
+				"missing name": ReactionError,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			outcomes := Check(context.Background(), testCase.config)
+
+			got := make(map[string]Reaction, len(outcomes))
+
+			for _, outcome := range outcomes {
+				got[outcome.Scenario.Name] = outcome.Reaction
+
+				if outcome.Reaction != testCase.expected[outcome.Scenario.Name] {
+					t.Errorf("unexpected reaction: %s", outcome)
+				}
+			}
+
+			for scenario, expected := range testCase.expected {
+				if _, ok := got[scenario]; !ok {
+					t.Errorf("expected scenario %q with reaction %s", scenario, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCheck_Result(t *testing.T) {
+	t.Parallel()
+
+	outcomes := Check(context.Background(), testConfig("truncate"))
+
+	for _, outcome := range outcomes {
+		if outcome.Scenario.Expected != ReactionResult {
+			continue
+		}
+
+		if !outcome.Conforms() {
+			t.Fatalf("unexpected reaction: %s", outcome)
+		}
+
+		for key, value := range outcome.Scenario.Query {
+			got, ok := outcome.Result[key]
+
+			if value == nil {
+				if ok {
+					t.Errorf("scenario %q: expected null key %q to be omitted, got %q", outcome.Scenario.Name, key, got)
+				}
+				continue
+			}
+
+			if !ok {
+				t.Errorf("scenario %q: expected key %q in result", outcome.Scenario.Name, key)
+				continue
+			}
+
+			if len(*value) > 1024 {
+				if len(got) != 1024 {
+					t.Errorf("scenario %q: expected truncated value of key %q, got %d bytes", outcome.Scenario.Name, key, len(got))
+				}
+				continue
+			}
+
+			if got != *value {
+				t.Errorf("scenario %q: expected value %q of key %q, got %q", outcome.Scenario.Name, *value, key, got)
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	Run(t, testConfig("echo"))
+}
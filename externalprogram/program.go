@@ -0,0 +1,307 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package externalprogram implements the program side of the protocol of the
+// external data source, so Go programs do not need to reimplement it.
+//
+// A program is a Go program which calls Run with an implementation of
+// Program. Run reads and validates the query from the standard input, or from
+// the environment with query_delivery = "environment", calls
+// Read and writes the result as a JSON object to the standard output, or
+// reports the error on the standard error and exits with a non-zero status:
+//
+//	func main() {
+//		externalprogram.Run(externalprogram.ProgramFunc(func(ctx context.Context, query externalprogram.Query) (externalprogram.Result, error) {
+//			name, err := query.Require("name")
+//			if err != nil {
+//				return nil, err
+//			}
+//
+//			return externalprogram.Result{"greeting": "Hello, " + name}, nil
+//		}))
+//	}
+//
+// Programs configured with persistent = true call RunPersistent instead,
+// which answers every JSON-RPC 2.0 request of the provider until the
+// standard input is closed.
+package externalprogram
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	// ExitFailure is the exit status of a program whose Read returned an
+	// error without an exit status.
+	ExitFailure = 1
+
+	// ExitPanic is the exit status of a program whose Read panicked, which
+	// matches the exit status of Go programs terminated by a panic.
+	ExitPanic = 2
+)
+
+// QueryEnvironmentPrefix prefixes the environment variables of query values
+// when the data source sets query_delivery = "environment". The query is then
+// not written to the standard input, and Run reads the values from the
+// environment instead, under the lower-cased names of the variables without
+// the prefix.
+const QueryEnvironmentPrefix = "TF_QUERY_"
+
+// Program is the interface implemented by external programs.
+type Program interface {
+	// Read returns the result of the data source for the query.
+	Read(ctx context.Context, query Query) (Result, error)
+}
+
+// ProgramFunc is a function implementing Program.
+type ProgramFunc func(ctx context.Context, query Query) (Result, error)
+
+// Read calls f.
+func (f ProgramFunc) Read(ctx context.Context, query Query) (Result, error) {
+	return f(ctx, query)
+}
+
+// Error is an error returned by a program which is reported on the standard
+// error with the given summary and detail. Errors other than *Error are
+// reported with their message.
+type Error struct {
+	// Summary is the short summary of the error.
+	Summary string `json:"summary,omitempty"`
+
+	// Detail is the detailed explanation of the error.
+	Detail string `json:"detail"`
+
+	// QueryKey is the key of the query the error relates to, if any.
+	QueryKey string `json:"query_key,omitempty"`
+
+	// ExitCode is the exit status of a program run with Run. ExitFailure is
+	// used if zero.
+	ExitCode int `json:"-"`
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+
+	if e.Summary != "" {
+		message = e.Summary + ": " + e.Detail
+	}
+
+	if e.QueryKey != "" {
+		message += fmt.Sprintf(" (query key %q)", e.QueryKey)
+	}
+
+	return message
+}
+
+// Run runs the program once for the query read from the standard input, or
+// from the environment if the standard input is empty, and exits. It must be called from the main function of the program.
+func Run(program Program) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	code := run(ctx, program, os.Environ(), os.Stdin, os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+// RunPersistent answers the read requests of the provider until the standard
+// input is closed, and exits. It must be called from the main function of
+// the program.
+func RunPersistent(program Program) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	code := runPersistent(ctx, program, os.Stdin, os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+// run runs the program once and returns its exit status.
+func run(ctx context.Context, program Program, environ []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return report(stderr, &Error{Summary: "Query Read Failed", Detail: err.Error()})
+	}
+
+	// The query is not written to the standard input when it is delivered
+	// through the environment or the arguments.
+	query := environmentQuery(environ)
+
+	if len(bytes.TrimSpace(input)) > 0 {
+		query, err = decodeQuery(input)
+		if err != nil {
+			return report(stderr, err)
+		}
+	}
+
+	result, err := read(ctx, program, query)
+	if err != nil {
+		return report(stderr, err)
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return report(stderr, &Error{Summary: "Result Encoding Failed", Detail: err.Error()})
+	}
+
+	if _, err := stdout.Write(output); err != nil {
+		return report(stderr, &Error{Summary: "Result Write Failed", Detail: err.Error()})
+	}
+
+	return 0
+}
+
+// read calls the program, converting panics into errors.
+func read(ctx context.Context, program Program, query Query) (result Result, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &Error{Summary: "Program Panicked", Detail: fmt.Sprint(recovered), ExitCode: ExitPanic}
+		}
+	}()
+
+	result, err = program.Read(ctx, query)
+
+	if err == nil && result == nil {
+		result = Result{}
+	}
+
+	return result, err
+}
+
+// report writes the error to the standard error and returns the exit status
+// of the program.
+func report(stderr io.Writer, err error) int {
+	var programErr *Error
+
+	if !errors.As(err, &programErr) {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+
+	fmt.Fprintln(stderr, programErr)
+
+	if programErr.ExitCode != 0 {
+		return programErr.ExitCode
+	}
+
+	return ExitFailure
+}
+
+// JSON-RPC 2.0 error codes of the persistent program protocol.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+
+	// rpcReadError is the code of errors returned by Read.
+	rpcReadError = 1
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+
+	// Result is an interface, so empty results are not omitted.
+	Result interface{} `json:"result,omitempty"`
+	Error  *rpcError   `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *Error `json:"data,omitempty"`
+}
+
+// runPersistent answers requests until the standard input is closed and
+// returns the exit status of the program.
+func runPersistent(ctx context.Context, program Program, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	// Lines are read without a size limit, as query values can be large.
+	reader := bufio.NewReader(stdin)
+	encoder := json.NewEncoder(stdout)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			response := answer(ctx, program, line)
+
+			if encodeErr := encoder.Encode(response); encodeErr != nil {
+				return report(stderr, &Error{Summary: "Response Write Failed", Detail: encodeErr.Error()})
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return 0
+		}
+
+		if err != nil {
+			return report(stderr, &Error{Summary: "Request Read Failed", Detail: err.Error()})
+		}
+	}
+}
+
+// answer returns the response to a single request.
+func answer(ctx context.Context, program Program, line []byte) rpcResponse {
+	response := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+
+	var request rpcRequest
+
+	if err := json.Unmarshal(line, &request); err != nil {
+		response.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		return response
+	}
+
+	if len(request.ID) > 0 {
+		response.ID = request.ID
+	}
+
+	if request.JSONRPC != "2.0" {
+		response.Error = &rpcError{Code: rpcInvalidRequest, Message: fmt.Sprintf("unsupported JSON-RPC version %q", request.JSONRPC)}
+		return response
+	}
+
+	if request.Method != "read" {
+		response.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+		return response
+	}
+
+	query := Query{}
+
+	if len(request.Params) > 0 && string(request.Params) != "null" {
+		var err error
+
+		query, err = decodeQuery(request.Params)
+		if err != nil {
+			response.Error = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			return response
+		}
+	}
+
+	result, err := read(ctx, program, query)
+	if err != nil {
+		response.Error = &rpcError{Code: rpcReadError, Message: err.Error()}
+		errors.As(err, &response.Error.Data)
+		return response
+	}
+
+	response.Result = result
+
+	return response
+}
@@ -0,0 +1,179 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalprogram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testProgram = ProgramFunc(func(_ context.Context, query Query) (Result, error) {
+	if _, ok := query.Lookup("fail"); ok {
+		return nil, errors.New("I was asked to fail")
+	}
+
+	if _, ok := query.Lookup("panic"); ok {
+		panic("I was asked to panic")
+	}
+
+	if _, ok := query.Lookup("empty"); ok {
+		return nil, nil
+	}
+
+	port, err := query.Int("port", 8080)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Result{"value": query.Get("value", "default")}
+	result.SetInt("port", port)
+
+	return result, nil
+})
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		environ        []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		"query": {
+			stdin:          `{"value":"pizza","port":"443"}`,
+			expectedStdout: `{"port":"443","value":"pizza"}`,
+		},
+		"empty input": {
+			expectedStdout: `{"port":"8080","value":"default"}`,
+		},
+		"environment": {
+			environ:        []string{"PATH=/bin", "TF_QUERY_VALUE=pizza", "TF_QUERY_PORT=443", "TF_QUERY_=ignored"},
+			expectedStdout: `{"port":"443","value":"pizza"}`,
+		},
+		"environment with stdin": {
+			environ:        []string{"TF_QUERY_VALUE=pizza"},
+			stdin:          `{"port":"443"}`,
+			expectedStdout: `{"port":"443","value":"default"}`,
+		},
+		"null value": {
+			stdin:          `{"value":null}`,
+			expectedStdout: `{"port":"8080","value":"default"}`,
+		},
+		"empty result": {
+			stdin:          `{"empty":""}`,
+			expectedStdout: `{}`,
+		},
+		"invalid json": {
+			stdin:          `{"value":`,
+			expectedCode:   ExitFailure,
+			expectedStderr: "Invalid Query: The query must be a JSON object with string values: unexpected EOF\n",
+		},
+		"non-string value": {
+			stdin:          `{"port":443}`,
+			expectedCode:   ExitFailure,
+			expectedStderr: "Invalid Query: The query value must be a string, got json.Number. (query key \"port\")\n",
+		},
+		"typed value": {
+			stdin:          `{"port":"https"}`,
+			expectedCode:   ExitFailure,
+			expectedStderr: "Invalid Query Value: The query value must be an integer, got \"https\". (query key \"port\")\n",
+		},
+		"error": {
+			stdin:          `{"fail":""}`,
+			expectedCode:   ExitFailure,
+			expectedStderr: "I was asked to fail\n",
+		},
+		"panic": {
+			stdin:          `{"panic":""}`,
+			expectedCode:   ExitPanic,
+			expectedStderr: "Program Panicked: I was asked to panic\n",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr strings.Builder
+
+			code := run(context.Background(), testProgram, testCase.environ, strings.NewReader(testCase.stdin), &stdout, &stderr)
+
+			if code != testCase.expectedCode {
+				t.Errorf("expected exit status %d, got %d", testCase.expectedCode, code)
+			}
+
+			if diff := cmp.Diff(testCase.expectedStdout, stdout.String()); diff != "" {
+				t.Errorf("unexpected stdout difference: %s", diff)
+			}
+
+			if diff := cmp.Diff(testCase.expectedStderr, stderr.String()); diff != "" {
+				t.Errorf("unexpected stderr difference: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRun_ExitCode(t *testing.T) {
+	t.Parallel()
+
+	program := ProgramFunc(func(_ context.Context, _ Query) (Result, error) {
+		return nil, &Error{Summary: "Not Found", Detail: "The record does not exist.", QueryKey: "id", ExitCode: 3}
+	})
+
+	var stdout, stderr strings.Builder
+
+	code := run(context.Background(), program, nil, strings.NewReader(`{"id":"42"}`), &stdout, &stderr)
+
+	if code != 3 {
+		t.Errorf("expected exit status 3, got %d", code)
+	}
+
+	if diff := cmp.Diff("Not Found: The record does not exist. (query key \"id\")\n", stderr.String()); diff != "" {
+		t.Errorf("unexpected stderr difference: %s", diff)
+	}
+}
+
+func TestRunPersistent(t *testing.T) {
+	t.Parallel()
+
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"read","params":{"value":"pizza"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"read","params":{"fail":""}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"read","params":{"port":"https"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"write","params":{}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"read","params":{"port":443}}`,
+		`not json`,
+		``,
+		`{"jsonrpc":"2.0","id":6,"method":"read","params":{"empty":""}}`,
+	}, "\n")
+
+	var stdout, stderr strings.Builder
+
+	code := runPersistent(context.Background(), testProgram, strings.NewReader(requests), &stdout, &stderr)
+
+	if code != 0 {
+		t.Errorf("expected exit status 0, got %d: %s", code, stderr.String())
+	}
+
+	expected := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"result":{"port":"8080","value":"pizza"}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":1,"message":"I was asked to fail"}}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":1,"message":"Invalid Query Value: The query value must be an integer, got \"https\". (query key \"port\")","data":{"summary":"Invalid Query Value","detail":"The query value must be an integer, got \"https\".","query_key":"port"}}}`,
+		`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"unknown method \"write\""}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"Invalid Query: The query value must be a string, got json.Number. (query key \"port\")"}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"invalid character 'o' in literal null (expecting 'u')"}}`,
+		`{"jsonrpc":"2.0","id":6,"result":{}}`,
+		``,
+	}, "\n")
+
+	if diff := cmp.Diff(expected, stdout.String()); diff != "" {
+		t.Errorf("unexpected stdout difference: %s", diff)
+	}
+}
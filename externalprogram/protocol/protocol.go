@@ -0,0 +1,104 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package protocol implements the provider side of the protocol of the
+// external data source: how a program is executed with the query and how its
+// output is decoded into the result.
+//
+// The provider and the externalprogramtest package both execute programs
+// with this package, so programs are verified against the rules Terraform
+// applies.
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// ErrTimeout is wrapped by the error of Execute when the program was
+// terminated because the context reached its deadline.
+var ErrTimeout = errors.New("program timed out")
+
+// ExitError is the error of Execute when the program exited with a non-zero
+// status or was terminated by a signal.
+type ExitError struct {
+	// Stderr is the standard error of the program, which explains the
+	// failure of a conforming program.
+	Stderr string
+
+	Err *exec.ExitError
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// HasMessage returns whether the program explained its failure on the
+// standard error.
+func (e *ExitError) HasMessage() bool {
+	return len(e.Stderr) > 0
+}
+
+// EncodeQuery encodes the query as written to the standard input of the
+// program.
+func EncodeQuery(query map[string]string) ([]byte, error) {
+	return json.Marshal(query)
+}
+
+// Execute runs the command, which must have been created with the context
+// to be terminated at its deadline, and returns its standard output and its
+// standard error. The input is written to the standard input of the program
+// if not nil. The standard error is also written to cmd.Stderr if set.
+//
+// The error wraps ErrTimeout if the context reached its deadline, or is an
+// *ExitError if the program failed on its own.
+func Execute(ctx context.Context, cmd *exec.Cmd, input []byte) ([]byte, string, error) {
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	var stderr strings.Builder
+
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, cmd.Stderr)
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	output, err := cmd.Output()
+
+	if err == nil {
+		return output, stderr.String(), nil
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, stderr.String(), fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+
+	var exitErr *exec.ExitError
+
+	if errors.As(err, &exitErr) {
+		return output, stderr.String(), &ExitError{Stderr: stderr.String(), Err: exitErr}
+	}
+
+	return output, stderr.String(), err
+}
+
+// DecodeResult decodes the output of a program into the result, which must
+// be a JSON object with string values.
+func DecodeResult(output []byte) (map[string]string, error) {
+	result := map[string]string{}
+	err := json.Unmarshal(output, &result)
+
+	return result, err
+}
@@ -0,0 +1,180 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// programMode is the argument running the test binary as an external program
+// with the behavior of the following argument.
+const programMode = "-protocol-program"
+
+func TestMain(m *testing.M) {
+	if len(os.Args) == 3 && os.Args[1] == programMode {
+		os.Exit(runProgram(os.Args[2]))
+	}
+
+	os.Exit(m.Run())
+}
+
+// runProgram implements the programs under test, returning the exit status.
+func runProgram(behavior string) int {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading query: %s\n", err)
+		return 1
+	}
+
+	switch behavior {
+	case "fail":
+		fmt.Fprintf(os.Stderr, "failed")
+		return 1
+	case "silent":
+		return 1
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+
+	fmt.Fprintf(os.Stdout, "%s", input)
+
+	return 0
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		behavior       string
+		timeout        time.Duration
+		expectedOutput string
+		expectedStderr string
+		expectedErr    func(error) bool
+	}{
+		"success": {
+			behavior:       "echo",
+			expectedOutput: `{"key":"value"}`,
+		},
+		"exit-error": {
+			behavior:       "fail",
+			expectedStderr: "failed",
+			expectedErr: func(err error) bool {
+				var exitErr *ExitError
+
+				return errors.As(err, &exitErr) && exitErr.HasMessage() && exitErr.Stderr == "failed"
+			},
+		},
+		"exit-error-without-message": {
+			behavior: "silent",
+			expectedErr: func(err error) bool {
+				var exitErr *ExitError
+
+				return errors.As(err, &exitErr) && !exitErr.HasMessage()
+			},
+		},
+		"timeout": {
+			behavior: "sleep",
+			timeout:  100 * time.Millisecond,
+			expectedErr: func(err error) bool {
+				return errors.Is(err, ErrTimeout)
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			if testCase.timeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, testCase.timeout)
+				defer cancel()
+			}
+
+			input, err := EncodeQuery(map[string]string{"key": "value"})
+			if err != nil {
+				t.Fatalf("unexpected error encoding query: %s", err)
+			}
+
+			cmd := exec.CommandContext(ctx, os.Args[0], programMode, testCase.behavior)
+
+			output, stderr, err := Execute(ctx, cmd, input)
+
+			if testCase.expectedErr == nil && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if testCase.expectedErr != nil && !testCase.expectedErr(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if testCase.expectedErr == nil && string(output) != testCase.expectedOutput {
+				t.Errorf("expected output %q, got %q", testCase.expectedOutput, output)
+			}
+
+			if stderr != testCase.expectedStderr {
+				t.Errorf("expected stderr %q, got %q", testCase.expectedStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestExecute_MissingProgram(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, "tf-acc-external-missing-program")
+
+	_, _, err := Execute(ctx, cmd, nil)
+
+	var exitErr *ExitError
+
+	if err == nil || errors.As(err, &exitErr) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected an error starting the program, got %v", err)
+	}
+}
+
+func TestDecodeResult(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		output    string
+		expectErr bool
+	}{
+		"object":         {output: `{"key":"value"}`},
+		"empty-object":   {output: `{}`},
+		"invalid-json":   {output: `not json`, expectErr: true},
+		"empty":          {output: ``, expectErr: true},
+		"number-value":   {output: `{"key":1}`, expectErr: true},
+		"object-value":   {output: `{"key":{"nested":"value"}}`, expectErr: true},
+		"array":          {output: `["value"]`, expectErr: true},
+		"trailing-value": {output: `{"key":"value"} {}`, expectErr: true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := DecodeResult([]byte(testCase.output))
+
+			if testCase.expectErr && err == nil {
+				t.Fatal("expected error, got none")
+			}
+
+			if !testCase.expectErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalprogram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is the query of the data source, which maps keys to string values.
+type Query map[string]string
+
+// decodeQuery decodes and validates a query, which must be a JSON object
+// with string values. Null values are treated as missing keys, as the
+// provider never sends them.
+func decodeQuery(data []byte) (Query, error) {
+	var values map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&values); err != nil {
+		return nil, &Error{
+			Summary: "Invalid Query",
+			Detail:  fmt.Sprintf("The query must be a JSON object with string values: %s", err),
+		}
+	}
+
+	if values == nil {
+		return nil, &Error{
+			Summary: "Invalid Query",
+			Detail:  "The query must be a JSON object with string values, not null.",
+		}
+	}
+
+	query := make(Query, len(values))
+
+	for key, value := range values {
+		switch value := value.(type) {
+		case string:
+			query[key] = value
+		case nil:
+		default:
+			return nil, &Error{
+				Summary:  "Invalid Query",
+				Detail:   fmt.Sprintf("The query value must be a string, got %T.", value),
+				QueryKey: key,
+			}
+		}
+	}
+
+	return query, nil
+}
+
+// environmentQuery returns the query delivered through the environment
+// variables prefixed with QueryEnvironmentPrefix. The provider upper-cases the
+// keys of the query, so they are lower-cased again.
+func environmentQuery(environ []string) Query {
+	query := Query{}
+
+	for _, variable := range environ {
+		name, value, ok := strings.Cut(variable, "=")
+		if !ok || name == QueryEnvironmentPrefix || !strings.HasPrefix(name, QueryEnvironmentPrefix) {
+			continue
+		}
+
+		query[strings.ToLower(strings.TrimPrefix(name, QueryEnvironmentPrefix))] = value
+	}
+
+	return query
+}
+
+// Lookup returns the value of the key and whether it is set.
+func (q Query) Lookup(key string) (string, bool) {
+	value, ok := q[key]
+
+	return value, ok
+}
+
+// Get returns the value of the key, or fallback if it is not set.
+func (q Query) Get(key string, fallback string) string {
+	if value, ok := q[key]; ok {
+		return value
+	}
+
+	return fallback
+}
+
+// Require returns the value of the key, or an error naming the key if it is
+// not set or empty.
+func (q Query) Require(key string) (string, error) {
+	value := q[key]
+
+	if value == "" {
+		return "", &Error{
+			Summary:  "Missing Query Value",
+			Detail:   "The query must set a non-empty value for the key.",
+			QueryKey: key,
+		}
+	}
+
+	return value, nil
+}
+
+// Int returns the value of the key as a base 10 integer, or fallback if it
+// is not set.
+func (q Query) Int(key string, fallback int64) (int64, error) {
+	value, ok := q[key]
+	if !ok {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, q.invalid(key, "an integer", value)
+	}
+
+	return parsed, nil
+}
+
+// Bool returns the value of the key as a boolean, such as true or false, or
+// fallback if it is not set.
+func (q Query) Bool(key string, fallback bool) (bool, error) {
+	value, ok := q[key]
+	if !ok {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, q.invalid(key, "a boolean", value)
+	}
+
+	return parsed, nil
+}
+
+// Duration returns the value of the key as a duration, such as 30s, or
+// fallback if it is not set.
+func (q Query) Duration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := q[key]
+	if !ok {
+		return fallback, nil
+	}
+
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, q.invalid(key, "a duration", value)
+	}
+
+	return parsed, nil
+}
+
+// List returns the value of the key split by the separator, without empty
+// elements, or nil if it is not set.
+func (q Query) List(key string, separator string) []string {
+	value, ok := q[key]
+	if !ok {
+		return nil
+	}
+
+	var elements []string
+
+	for _, element := range strings.Split(value, separator) {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
+
+// JSON decodes the value of the key, which is usually encoded with
+// jsonencode in the Terraform configuration, into target. The target is left
+// unchanged if the key is not set.
+func (q Query) JSON(key string, target interface{}) error {
+	value, ok := q[key]
+	if !ok {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return &Error{
+			Summary:  "Invalid Query Value",
+			Detail:   fmt.Sprintf("The query value must be JSON: %s", err),
+			QueryKey: key,
+		}
+	}
+
+	return nil
+}
+
+// AllowKeys returns an error naming the first unknown key, in lexical order,
+// if the query contains keys other than the given keys.
+func (q Query) AllowKeys(keys ...string) error {
+	allowed := make(map[string]bool, len(keys))
+
+	for _, key := range keys {
+		allowed[key] = true
+	}
+
+	var unknown string
+
+	for key := range q {
+		if !allowed[key] && (unknown == "" || key < unknown) {
+			unknown = key
+		}
+	}
+
+	if unknown != "" {
+		return &Error{
+			Summary:  "Unsupported Query Key",
+			Detail:   fmt.Sprintf("The query key is not supported. Supported keys: %s", strings.Join(keys, ", ")),
+			QueryKey: unknown,
+		}
+	}
+
+	return nil
+}
+
+func (q Query) invalid(key string, expected string, value string) error {
+	return &Error{
+		Summary:  "Invalid Query Value",
+		Detail:   fmt.Sprintf("The query value must be %s, got %q.", expected, value),
+		QueryKey: key,
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalprogram
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	query := Query{
+		"name":    "example",
+		"empty":   "",
+		"count":   " 3 ",
+		"enabled": "true",
+		"timeout": "30s",
+		"tags":    "a, b,,c",
+		"config":  `{"replicas":2}`,
+		"invalid": "not valid",
+	}
+
+	if _, err := query.Require("name"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	for _, key := range []string{"empty", "missing"} {
+		var programErr *Error
+
+		if _, err := query.Require(key); !errors.As(err, &programErr) || programErr.QueryKey != key {
+			t.Errorf("expected error for key %q, got: %v", key, err)
+		}
+	}
+
+	if value := query.Get("missing", "fallback"); value != "fallback" {
+		t.Errorf("expected fallback, got %q", value)
+	}
+
+	if value, err := query.Int("count", 0); err != nil || value != 3 {
+		t.Errorf("expected 3, got %d: %v", value, err)
+	}
+
+	if value, err := query.Bool("enabled", false); err != nil || !value {
+		t.Errorf("expected true, got %t: %v", value, err)
+	}
+
+	if value, err := query.Duration("timeout", 0); err != nil || value != 30*time.Second {
+		t.Errorf("expected 30s, got %s: %v", value, err)
+	}
+
+	if value, err := query.Duration("missing", time.Minute); err != nil || value != time.Minute {
+		t.Errorf("expected fallback, got %s: %v", value, err)
+	}
+
+	if _, err := query.Int("invalid", 0); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	if _, err := query.Bool("invalid", false); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	if diff := cmp.Diff([]string{"a", "b", "c"}, query.List("tags", ",")); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	var config struct {
+		Replicas int `json:"replicas"`
+	}
+
+	if err := query.JSON("config", &config); err != nil || config.Replicas != 2 {
+		t.Errorf("expected 2 replicas, got %d: %v", config.Replicas, err)
+	}
+
+	if err := query.JSON("invalid", &config); err == nil {
+		t.Error("expected error, got none")
+	}
+
+	var programErr *Error
+
+	if err := query.AllowKeys("name", "empty", "count", "enabled", "timeout", "tags"); !errors.As(err, &programErr) || programErr.QueryKey != "config" {
+		t.Errorf("expected error for key config, got: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package externalprogram
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Result is the result of the data source, which maps keys to string values.
+// Other values are encoded as strings, to be decoded in the Terraform
+// configuration, such as with tonumber or jsondecode.
+type Result map[string]string
+
+// Set sets the value of the key.
+func (r Result) Set(key string, value string) {
+	r[key] = value
+}
+
+// SetInt sets the value of the key to a base 10 integer.
+func (r Result) SetInt(key string, value int64) {
+	r[key] = strconv.FormatInt(value, 10)
+}
+
+// SetBool sets the value of the key to true or false.
+func (r Result) SetBool(key string, value bool) {
+	r[key] = strconv.FormatBool(value)
+}
+
+// SetJSON sets the value of the key to the JSON encoding of the value, which
+// can be decoded with jsondecode in the Terraform configuration.
+func (r Result) SetJSON(key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	r[key] = string(encoded)
+
+	return nil
+}
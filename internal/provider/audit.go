@@ -0,0 +1,172 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// auditRedactedValue replaces sensitive values in audit records.
+const auditRedactedValue = "(redacted)"
+
+// auditSensitiveArgRegexp matches command line arguments in the form of
+// name=value, or --name=value, whose name suggests a credential.
+var auditSensitiveArgRegexp = regexp.MustCompile(`(?i)^(-{0,2}[a-z0-9_.-]*(?:password|passwd|secret|token|credential|api[_-]?key|private[_-]?key)[a-z0-9_.-]*=).+$`)
+
+// auditSensitiveFlagRegexp matches command line flags whose name suggests a
+// credential, such as --token, whose value is the following argument. The
+// -p flag commonly sets a password, such as for mysql and sshpass.
+var auditSensitiveFlagRegexp = regexp.MustCompile(`(?i)^(?:-{1,2}[a-z0-9_.-]*(?:password|passwd|secret|token|credential|api[_-]?key|private[_-]?key)[a-z0-9_.-]*|-p)$`)
+
+// auditLog appends a JSON Lines record for every executed program, or query
+// sent to an endpoint, to a file.
+type auditLog struct {
+	path string
+
+	// mu serializes writes from concurrently read data sources so records
+	// are never interleaved.
+	mu sync.Mutex
+}
+
+// auditRecord is a single line of the audit log. Query values and program
+// output are never recorded, only the query keys and a digest of the output.
+type auditRecord struct {
+	Time         time.Time `json:"time"`
+	Program      string    `json:"program"`
+	Args         []string  `json:"args"`
+	WorkingDir   string    `json:"working_dir"`
+	QueryKeys    []string  `json:"query_keys"`
+	ExitStatus   int       `json:"exit_status"`
+	DurationMs   int64     `json:"duration_ms"`
+	StdoutBytes  int       `json:"stdout_bytes"`
+	StderrBytes  int       `json:"stderr_bytes"`
+	ResultSHA256 string    `json:"result_sha256,omitempty"`
+
+	// HTTPStatus is the response status code of endpoints.
+	HTTPStatus int `json:"http_status,omitempty"`
+}
+
+// newAuditLog verifies the audit log file can be opened for appending,
+// creating it if necessary.
+func newAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return &auditLog{path: path}, nil
+}
+
+// newAuditRecord creates an audit record for a program execution. The
+// result digest is only populated when the program succeeded.
+func newAuditRecord(start time.Time, program string, args []string, workingDir string, query map[string]string, exitStatus int, stdout []byte, stderrBytes int) auditRecord {
+	if workingDir == "" {
+		workingDir, _ = os.Getwd()
+	}
+
+	redactedArgs := make([]string, len(args))
+
+	for i, arg := range args {
+		if i > 0 && auditSensitiveFlagRegexp.MatchString(args[i-1]) {
+			redactedArgs[i] = auditRedactedValue
+			continue
+		}
+
+		redactedArgs[i] = auditSensitiveArgRegexp.ReplaceAllString(arg, "${1}"+auditRedactedValue)
+	}
+
+	queryKeys := make([]string, 0, len(query))
+
+	for key := range query {
+		queryKeys = append(queryKeys, key)
+	}
+
+	sort.Strings(queryKeys)
+
+	record := auditRecord{
+		Time:        start.UTC(),
+		Program:     program,
+		Args:        redactedArgs,
+		WorkingDir:  workingDir,
+		QueryKeys:   queryKeys,
+		ExitStatus:  exitStatus,
+		DurationMs:  time.Since(start).Milliseconds(),
+		StdoutBytes: len(stdout),
+		StderrBytes: stderrBytes,
+	}
+
+	if exitStatus == 0 {
+		digest := sha256.Sum256(stdout)
+		record.ResultSHA256 = hex.EncodeToString(digest[:])
+	}
+
+	return record
+}
+
+// Write appends the record to the audit log.
+func (l *auditLog) Write(record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to encode audit record: %w", err)
+	}
+
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(line)
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writeAuditRecord appends the record to the audit log of the provider, if
+// configured, with the sensitive values of the invocation masked. The results
+// of the execution must be discarded if this fails.
+func (n *externalDataSource) writeAuditRecord(invocation programInvocation, record auditRecord, location string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if n.providerData.AuditLog == nil {
+		return diags
+	}
+
+	if invocation.Redactor != nil {
+		record = invocation.Redactor.redactAuditRecord(record)
+	}
+
+	if err := n.providerData.AuditLog.Write(record); err != nil {
+		diags.AddError(
+			"Audit Log Write Failed",
+			"The data source received results, but was unable to append the execution record to the audit log "+
+				"configured in the provider with 'audit_log_path'. The results are discarded."+
+				fmt.Sprintf("\n\n%s", location)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+	}
+
+	return diags
+}
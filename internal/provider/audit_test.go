@@ -0,0 +1,103 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	record := newAuditRecord(
+		start,
+		"/opt/bin/helper",
+		[]string{"lookup", "--api-key=abc123", "password=hunter2", "--verbose", "name=value", "--token", "abc123", "-p", "hunter2", "-v", "value"},
+		"/work",
+		map[string]string{"region": "eu-west-1", "account": "1234"},
+		0,
+		[]byte(`{"result":"yes"}`),
+		5,
+	)
+
+	expectedArgs := []string{"lookup", "--api-key=(redacted)", "password=(redacted)", "--verbose", "name=value", "--token", "(redacted)", "-p", "(redacted)", "-v", "value"}
+
+	if diff := cmp.Diff(expectedArgs, record.Args); diff != "" {
+		t.Errorf("unexpected args difference: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"account", "region"}, record.QueryKeys); diff != "" {
+		t.Errorf("unexpected query keys difference: %s", diff)
+	}
+
+	if record.Time != start {
+		t.Errorf("unexpected time: %s", record.Time)
+	}
+
+	if record.StdoutBytes != 16 || record.StderrBytes != 5 {
+		t.Errorf("unexpected output sizes: %d, %d", record.StdoutBytes, record.StderrBytes)
+	}
+
+	// echo -n '{"result":"yes"}' | sha256sum
+	if record.ResultSHA256 != "0d11b4586da5bd118193ab7bf19f0e166cd089ddf51130f876d08204744452c4" {
+		t.Errorf("unexpected result digest: %s", record.ResultSHA256)
+	}
+
+	failed := newAuditRecord(start, "/opt/bin/helper", nil, "/work", nil, 1, nil, 0)
+
+	if failed.ResultSHA256 != "" {
+		t.Errorf("unexpected result digest for failed program: %s", failed.ResultSHA256)
+	}
+}
+
+func TestAuditLogWrite(t *testing.T) {
+	t.Parallel()
+
+	auditLogPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	auditLog, err := newAuditLog(auditLogPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, program := range []string{"first", "second"} {
+		err = auditLog.Write(auditRecord{Program: program})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	f, err := os.Open(auditLogPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	var programs []string
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		var record auditRecord
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("unexpected error decoding %q: %s", scanner.Text(), err)
+		}
+
+		programs = append(programs, record.Program)
+	}
+
+	if diff := cmp.Diff([]string{"first", "second"}, programs); diff != "" {
+		t.Errorf("unexpected records difference: %s", diff)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// programCredential is the user and group a program is executed as instead
+// of the user and group of the provider. Supplementary groups of the provider
+// are dropped.
+type programCredential struct {
+	Uid uint32 `json:"uid"`
+	Gid uint32 `json:"gid"`
+}
+
+// lookupRunAsUser returns the user ID and primary group ID of a user name or
+// numeric user ID. The primary group is unknown for numeric IDs without a
+// user account, in which case ok is false.
+func lookupRunAsUser(name string) (uid uint32, gid uint32, ok bool, err error) {
+	account, err := user.Lookup(name)
+
+	if err != nil {
+		id, parseErr := strconv.ParseUint(name, 10, 32)
+		if parseErr != nil {
+			return 0, 0, false, err
+		}
+
+		account, err = user.LookupId(name)
+		if err != nil {
+			return uint32(id), 0, false, nil
+		}
+	}
+
+	parsedUid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("user %q has the non-numeric ID %q", name, account.Uid)
+	}
+
+	parsedGid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return uint32(parsedUid), 0, false, nil
+	}
+
+	return uint32(parsedUid), uint32(parsedGid), true, nil
+}
+
+// lookupRunAsGroup returns the group ID of a group name or numeric group ID.
+func lookupRunAsGroup(name string) (uint32, error) {
+	group, err := user.LookupGroup(name)
+
+	if err != nil {
+		id, parseErr := strconv.ParseUint(name, 10, 32)
+		if parseErr != nil {
+			return 0, err
+		}
+
+		return uint32(id), nil
+	}
+
+	gid, err := strconv.ParseUint(group.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("group %q has the non-numeric ID %q", name, group.Gid)
+	}
+
+	return uint32(gid), nil
+}
+
+// runAsCredential resolves the user and group programs are executed as. The
+// group defaults to the primary group of the user, and the user to the user
+// of the provider. A nil credential is returned if neither is configured or
+// both match the provider, as programs then run as the provider.
+func runAsCredential(runAsUser string, runAsGroup string, userPath path.Path, groupPath path.Path) (*programCredential, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if runAsUser == "" && runAsGroup == "" {
+		return nil, diags
+	}
+
+	attributePath := userPath
+
+	if runAsUser == "" {
+		attributePath = groupPath
+	}
+
+	if !credentialSupported {
+		diags.AddAttributeError(
+			attributePath,
+			"Unsupported Run As User",
+			"Programs can only be executed as a different user or group on Unix platforms."+
+				fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+		)
+		return nil, diags
+	}
+
+	credential := &programCredential{
+		Uid: uint32(os.Geteuid()),
+		Gid: uint32(os.Getegid()),
+	}
+
+	if runAsUser != "" {
+		uid, gid, hasGroup, err := lookupRunAsUser(runAsUser)
+		if err != nil {
+			diags.AddAttributeError(
+				userPath,
+				"Invalid Run As User",
+				"The user could not be found. It must be either the name or the numeric ID of a user."+
+					fmt.Sprintf("\n\nUser: %s", runAsUser)+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			return nil, diags
+		}
+
+		if !hasGroup && runAsGroup == "" {
+			diags.AddAttributeError(
+				userPath,
+				"Invalid Run As User",
+				"The primary group of the user could not be determined. Configure the group with 'run_as_group'."+
+					fmt.Sprintf("\n\nUser: %s", runAsUser),
+			)
+			return nil, diags
+		}
+
+		credential.Uid = uid
+		credential.Gid = gid
+	}
+
+	if runAsGroup != "" {
+		gid, err := lookupRunAsGroup(runAsGroup)
+		if err != nil {
+			diags.AddAttributeError(
+				groupPath,
+				"Invalid Run As Group",
+				"The group could not be found. It must be either the name or the numeric ID of a group."+
+					fmt.Sprintf("\n\nGroup: %s", runAsGroup)+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			return nil, diags
+		}
+
+		credential.Gid = gid
+	}
+
+	if credential.Uid == uint32(os.Geteuid()) && credential.Gid == uint32(os.Getegid()) {
+		return nil, diags
+	}
+
+	if os.Geteuid() != 0 {
+		diags.AddAttributeError(
+			attributePath,
+			"Run As User Not Permitted",
+			"The provider is not running as root, so it cannot execute programs as a different user or group. "+
+				"Run Terraform as root, or remove 'run_as_user' and 'run_as_group' to execute programs as the user "+
+				"of the provider."+
+				fmt.Sprintf("\n\nProvider User: %d", os.Geteuid())+
+				fmt.Sprintf("\nRun As User: %d", credential.Uid)+
+				fmt.Sprintf("\nRun As Group: %d", credential.Gid),
+		)
+		return nil, diags
+	}
+
+	return credential, diags
+}
@@ -0,0 +1,33 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !unix
+
+package provider
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// credentialSupported is whether programs can be executed as a different
+// user or group on this platform.
+const credentialSupported = false
+
+// errCredentialUnsupported is returned when starting a program executed as a
+// different user, which is prevented by validation on this platform.
+var errCredentialUnsupported = errors.New("executing programs as a different user or group is only supported on Unix platforms")
+
+func applyCredential(cmd *exec.Cmd, credential *programCredential) {
+	if cmd.Err == nil {
+		cmd.Err = errCredentialUnsupported
+	}
+}
+
+func shareScript(scriptPath string, credential *programCredential) error {
+	return errCredentialUnsupported
+}
+
+func shareProgramCopy(copyPath string, credential *programCredential) error {
+	return errCredentialUnsupported
+}
@@ -0,0 +1,96 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestRunAsCredential(t *testing.T) {
+	t.Parallel()
+
+	if !credentialSupported {
+		t.Skip("executing programs as a different user is not supported on this platform")
+	}
+
+	currentUser := strconv.Itoa(os.Geteuid())
+	currentGroup := strconv.Itoa(os.Getegid())
+
+	testCases := map[string]struct {
+		user     string
+		group    string
+		root     bool
+		expected *programCredential
+		summary  string
+	}{
+		"none": {},
+		"provider user": {
+			user:  currentUser,
+			group: currentGroup,
+		},
+		"provider group": {
+			group: currentGroup,
+		},
+		"numeric ids": {
+			user:     "65534",
+			group:    "65534",
+			root:     true,
+			expected: &programCredential{Uid: 65534, Gid: 65534},
+		},
+		"unknown user": {
+			user:    "tf-acc-external-unknown-user",
+			summary: "Invalid Run As User",
+		},
+		"unknown group": {
+			group:   "tf-acc-external-unknown-group",
+			summary: "Invalid Run As Group",
+		},
+	}
+
+	if os.Geteuid() != 0 {
+		testCases["not permitted"] = struct {
+			user     string
+			group    string
+			root     bool
+			expected *programCredential
+			summary  string
+		}{
+			user:    "65534",
+			group:   "65534",
+			summary: "Run As User Not Permitted",
+		}
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if testCase.root && os.Geteuid() != 0 {
+				t.Skip("this test requires the tests to run as root")
+			}
+
+			credential, diags := runAsCredential(testCase.user, testCase.group, path.Root("run_as_user"), path.Root("run_as_group"))
+
+			if testCase.summary != "" {
+				if !diags.HasError() || diags.Errors()[0].Summary() != testCase.summary {
+					t.Fatalf("expected %q error, got: %v", testCase.summary, diags)
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if diff := cmp.Diff(testCase.expected, credential); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build unix
+
+package provider
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// credentialSupported is whether programs can be executed as a different
+// user or group on this platform.
+const credentialSupported = true
+
+// applyCredential modifies the command to execute the program as the user
+// and group of the credential, without supplementary groups.
+func applyCredential(cmd *exec.Cmd, credential *programCredential) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    credential.Uid,
+		Gid:    credential.Gid,
+		Groups: []uint32{},
+	}
+}
+
+// shareScript transfers the script and its private directory to the user
+// and group of the credential, so the script remains readable by the
+// program without being readable by other users.
+func shareScript(scriptPath string, credential *programCredential) error {
+	for _, name := range []string{filepath.Dir(scriptPath), scriptPath} {
+		if err := os.Chown(name, int(credential.Uid), int(credential.Gid)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shareProgramCopy makes the private copy of a verified program and its
+// directory readable by the group of the credential. The copy remains owned
+// by the user of the provider, so the program cannot modify it.
+func shareProgramCopy(copyPath string, credential *programCredential) error {
+	for name, mode := range map[string]os.FileMode{filepath.Dir(copyPath): 0o750, copyPath: 0o550} {
+		if err := os.Chown(name, -1, int(credential.Gid)); err != nil {
+			return err
+		}
+
+		if err := os.Chmod(name, mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build unix
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestRunProgram_Credential(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("this test requires the tests to run as root")
+	}
+
+	shell, err := os.ReadFile("/bin/sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shellDigest := sha256.Sum256(shell)
+
+	credential := &programCredential{Uid: 65534, Gid: 65534}
+
+	testCases := map[string]struct {
+		sandbox       *sandboxConfig
+		programSHA256 string
+	}{
+		"default": {},
+		"program_sha256": {
+			programSHA256: hex.EncodeToString(shellDigest[:]),
+		},
+	}
+
+	if runtime.GOOS == "linux" {
+		testCases["sandbox"] = struct {
+			sandbox       *sandboxConfig
+			programSHA256 string
+		}{
+			sandbox: &sandboxConfig{Namespaces: true, Credential: credential},
+		}
+
+		// The verified copy of the program is within /tmp.
+		testCases["sandbox-program_sha256"] = struct {
+			sandbox       *sandboxConfig
+			programSHA256 string
+		}{
+			sandbox:       &sandboxConfig{Namespaces: true, Credential: credential},
+			programSHA256: hex.EncodeToString(shellDigest[:]),
+		}
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// The directory of t.TempDir is only accessible to the user running
+			// the tests.
+			workingDir, err := os.MkdirTemp("", "tf-acc-external-credential-")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { _ = os.RemoveAll(workingDir) })
+
+			if err := os.Chown(workingDir, int(credential.Uid), int(credential.Gid)); err != nil {
+				t.Fatal(err)
+			}
+
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:       []string{"/bin/sh", "-c", `printf '{"user":"%s","groups":"%s"}' "$(id -u)" "$(id -G)"`},
+				WorkingDir:    workingDir,
+				AttributePath: path.Root("program"),
+				Credential:    credential,
+				Sandbox:       testCase.sandbox,
+				ProgramSHA256: testCase.programSHA256,
+			}
+
+			output, _, diags := n.runProgram(context.Background(), invocation)
+
+			if diags.HasError() {
+				if testCase.sandbox != nil {
+					t.Skipf("this test requires user namespaces: %v", diags)
+				}
+
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			result, err := decodeResult(outputFormatJSON, output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]string{
+				"user":   "65534",
+				"groups": "65534",
+			}
+
+			if diff := cmp.Diff(expected, result); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
@@ -4,33 +4,48 @@
 package provider
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var (
-	_ datasource.DataSource = (*externalDataSource)(nil)
+	_ datasource.DataSource                     = (*externalDataSource)(nil)
+	_ datasource.DataSourceWithConfigure        = (*externalDataSource)(nil)
+	_ datasource.DataSourceWithConfigValidators = (*externalDataSource)(nil)
+	_ datasource.DataSourceWithValidateConfig   = (*externalDataSource)(nil)
 )
 
 func NewExternalDataSource() datasource.DataSource {
-	return &externalDataSource{}
+	return &externalDataSource{
+		providerData: &externalProviderData{
+			Shell:               defaultShell(),
+			EnvironmentScrubber: newEnvironmentScrubber(nil, nil),
+		},
+	}
 }
 
-type externalDataSource struct{}
+type externalDataSource struct {
+	providerData *externalProviderData
+}
 
 func (n *externalDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName
@@ -60,21 +75,211 @@ func (n *externalDataSource) Schema(ctx context.Context, req datasource.SchemaRe
 					"not execute the program through a shell, so it is not necessary to escape shell " +
 					"metacharacters nor add quotes around arguments containing spaces.",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+
+			"profile": schema.StringAttribute{
+				Description: "The name of a program profile declared in the provider configuration. The " +
+					"profile supplies the program to run along with its default query, environment, working " +
+					"directory and timeout. Exactly one of `program`, `profile`, `command`, `script`, `plugin` or `endpoint` must be set.",
+				Optional: true,
+			},
+
+			"command": schema.StringAttribute{
+				Description: "A command line to execute through the shell configured with the `shell` " +
+					"attribute of the provider, which defaults to `/bin/sh -c` on Unix-based platforms. The " +
+					"values of the query are passed as positional parameters (`$1`, `$2`, ...) ordered by " +
+					"their keys, in addition to being written to the standard input of the command, so they " +
+					"never need to be quoted or escaped within the command. Exactly one of `program`, " +
+					"`profile`, `command`, `script`, `plugin` or `endpoint` must be set.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"script": schema.StringAttribute{
+				Description: "The content of a script to execute, such as a heredoc. The script is written to a " +
+					"file in a private temporary directory, executed with `interpreter` following the same " +
+					"protocol as `program`, and deleted afterwards. Exactly one of `program`, `profile`, " +
+					"`command`, `script`, `plugin` or `endpoint` must be set.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"interpreter": schema.ListAttribute{
+				Description: "A list of strings, whose first element is the interpreter executing the `script` and " +
+					"whose subsequent elements are optional command line arguments, such as `[\"python3\"]`. The " +
+					"path of the script file is appended as the last argument. If not supplied, the script is " +
+					"executed directly and must start with an interpreter directive such as `#!/bin/sh`.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.AlsoRequires(path.MatchRoot("script")),
+				},
+			},
+
+			"plugin": schema.ListAttribute{
+				Description: "A list of strings, whose first element is a Go program serving a helper with the " +
+					"`externalplugin` package and whose subsequent elements are optional command line arguments. " +
+					"The program is started once, completes a handshake with the provider and then receives every " +
+					"read of data sources with the same plugin, working directory and environment over gRPC. " +
+					"Exactly one of `program`, `profile`, `command`, `script`, `plugin` or `endpoint` must be set.",
+				ElementType: types.StringType,
+				Optional:    true,
 				Validators: []validator.List{
 					listvalidator.SizeAtLeast(1),
 				},
 			},
 
+			"endpoint": schema.StringAttribute{
+				Description: "A local endpoint to send the query to instead of executing a program. The endpoint " +
+					"is either a HTTP URL of a loopback address, such as `http://127.0.0.1:8080/query`, or the path " +
+					"of a Unix domain socket, such as `unix:///run/helper.sock`. The query is sent as the JSON body " +
+					"of a `POST` request and a successful response must have a body following the same protocol as " +
+					"the output of a program. Exactly one of `program`, `profile`, `command`, `script`, `plugin` or `endpoint` must be set.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"timeout": schema.StringAttribute{
+				Description: "The maximum duration to wait for results, such as `30s` or `5m`, after which the " +
+					"program is terminated or the request to the endpoint is cancelled. If not supplied, the " +
+					"timeout of the profile is used, if any, and otherwise there is no time limit.",
+				Optional: true,
+			},
+
+			"persistent": schema.BoolAttribute{
+				Description: "Whether to keep the program running after the read and reuse it for every data source " +
+					"with the same program, working directory and environment, instead of executing the program for " +
+					"every read. The program must then exchange newline-delimited JSON-RPC 2.0 messages over its " +
+					"standard input and output. Cannot be combined with `command` or `endpoint`.",
+				Optional: true,
+			},
+
+			"query_delivery": schema.StringAttribute{
+				Description: "How the query is delivered to the program. With `stdin`, the default, the query is " +
+					"written to the standard input of the program as a JSON object. With `environment`, each value " +
+					"is exposed as an environment variable named after its upper-cased key with the `TF_QUERY_` " +
+					"prefix, such as `TF_QUERY_REGION`. With `arguments`, placeholders in the form `{{ .key }}` in " +
+					"the program arguments are substituted with the query values, which may only start with `-` " +
+					"after a `--` argument. Other template actions are not supported. Only `stdin` is supported by " +
+					"`command`, `plugin`, `endpoint` and `persistent`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(queryDeliveryStdin, queryDeliveryEnvironment, queryDeliveryArguments),
+				},
+			},
+
+			"output_format": schema.StringAttribute{
+				Description: "The format of the output of the program, which is one of `json`, the default, " +
+					"`yaml`, `toml`, `dotenv` for `KEY=VALUE` lines or `properties` for Java properties. " +
+					"Every format must decode into a map of keys and scalar values, which are converted to strings. " +
+					"Cannot be combined with `plugin` or `persistent`, which always return JSON.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(outputFormats...),
+				},
+			},
+
+			"raw_output": schema.BoolAttribute{
+				Description: "Whether to expose the output of the program as is, instead of decoding it into " +
+					"`result`. The standard output and standard error of the program are then available as " +
+					"`stdout`, `stdout_base64` and `stderr`, and `result` is null. Cannot be combined with " +
+					"`plugin`, `persistent`, `endpoint` or `output_format`.",
+				Optional: true,
+			},
+
+			"allowed_read_paths": schema.ListAttribute{
+				Description: "A list of files and directories the program can read and execute, restricting the " +
+					"filesystem access of the program with Landlock on Linux. The program and script themselves " +
+					"are always readable. If either `allowed_read_paths` or `allowed_write_paths` is set, every " +
+					"other path is inaccessible, including the shared libraries and interpreters the program " +
+					"depends on. If Landlock is unavailable, a warning is returned and the program is executed " +
+					"without restrictions. Cannot be combined with `plugin` or `endpoint`.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"allowed_write_paths": schema.ListAttribute{
+				Description: "A list of files and directories the program can read, execute and modify, " +
+					"restricting the filesystem access of the program with Landlock on Linux. Cannot be combined " +
+					"with `plugin` or `endpoint`.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"seccomp_profile": schema.StringAttribute{
+				Description: "The seccomp profile restricting the system calls of the program on Linux on amd64 " +
+					"and arm64. Either `no-network`, which only allows Unix domain sockets, `no-exec`, which " +
+					"terminates processes of the program attempting to execute other programs, or the path of a JSON profile in the " +
+					"format of the OCI runtime specification, where rules with argument conditions, `includes` or " +
+					"`excludes` are not supported. Programs terminated for a denied system call return a " +
+					"dedicated error. Cannot be combined with `plugin`, `endpoint` or `persistent`.",
+				Optional: true,
+			},
+
+			"run_as_user": schema.StringAttribute{
+				Description: "The name or numeric ID of the user the program is executed as, overriding the " +
+					"`run_as_user` of the provider. The group defaults to the primary group of the user. This " +
+					"requires the provider to run as root, and is only supported on Unix platforms. A `script` is " +
+					"made readable by the user. Cannot be combined with `endpoint`.",
+				Optional: true,
+			},
+
+			"run_as_group": schema.StringAttribute{
+				Description: "The name or numeric ID of the group the program is executed as, overriding the " +
+					"`run_as_group` of the provider. Supplementary groups of the provider are dropped. Cannot be " +
+					"combined with `endpoint`.",
+				Optional: true,
+			},
+
+			"program_sha256": schema.StringAttribute{
+				Description: "The expected SHA-256 digest of the program, encoded as hexadecimal. The file found for " +
+					"the first element of the program, which is the interpreter of a `script` or the shell of a " +
+					"`command` if set, is verified before it is executed and is not executed on mismatch. The file is " +
+					"copied to a private temporary directory while it is verified, and the copy is executed, so the " +
+					"file cannot be replaced or modified between verification and execution. Cannot be combined with " +
+					"`endpoint`.",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(sha256DigestRegexp, "must be a SHA-256 digest encoded as 64 hexadecimal characters"),
+				},
+			},
+
+			"program_signature": schema.StringAttribute{
+				Description: "The path of a detached signature of the program, verified with the trusted keys of the " +
+					"`program_signatures` block of the provider before the program is executed. OpenPGP signatures " +
+					"can be binary or ASCII armored, and ed25519 signatures raw or base64 encoded. The program is not " +
+					"executed if the signature is invalid. Cannot be combined with `endpoint`.",
+				Optional: true,
+			},
+
 			"working_dir": schema.StringAttribute{
 				Description: "Working directory of the program. If not supplied, the program will run " +
-					"in the current directory.",
+					"in the working directory of the profile, if any, or otherwise in the current directory.",
 				Optional: true,
 			},
 
 			"query": schema.MapAttribute{
 				Description: "A map of string values to pass to the external program as the query " +
-					"arguments. If not supplied, the program will receive an empty object as its input.",
+					"arguments. When a profile is used, these values are merged on top of the query of the " +
+					"profile. If not supplied, the program will receive an empty object as its input.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"sensitive_query_keys": schema.ListAttribute{
+				Description: "Keys of the query whose values are masked in logs, diagnostics and audit log records, including the " +
+					"program arguments, output and standard error of the program.",
 				ElementType: types.StringType,
 				Optional:    true,
 			},
@@ -85,15 +290,233 @@ func (n *externalDataSource) Schema(ctx context.Context, req datasource.SchemaRe
 				Computed:    true,
 			},
 
+			"stdout": schema.StringAttribute{
+				Description: "The standard output of the program when `raw_output` is enabled. Invalid UTF-8 " +
+					"sequences are replaced with the Unicode replacement character, so binary output should be " +
+					"read from `stdout_base64` instead.",
+				Computed: true,
+			},
+
+			"stdout_base64": schema.StringAttribute{
+				Description: "The standard output of the program encoded with base64 when `raw_output` is enabled, " +
+					"which preserves binary output exactly.",
+				Computed: true,
+			},
+
+			"stderr": schema.StringAttribute{
+				Description: "The standard error of the program when `raw_output` is enabled.",
+				Computed:    true,
+			},
+
 			"id": schema.StringAttribute{
 				Description: "The id of the data source. This will always be set to `-`",
 				Computed:    true,
 			},
 		},
+
+		Blocks: map[string]schema.Block{
+			"sandbox": schema.SingleNestedBlock{
+				Description: "Runs the program in a sandbox, which is only supported on Linux. The program is " +
+					"started in new user, mount, PID and network namespaces, where the root filesystem is read-only " +
+					"except for the working directory, `/tmp` is private and empty, and the program has no network " +
+					"access. Cannot be combined with `plugin` or `endpoint`.",
+				Attributes: map[string]schema.Attribute{
+					"network": schema.BoolAttribute{
+						Description: "Whether the program keeps access to the network of the provider. Defaults to " +
+							"`false`. Cannot be enabled if the `sandbox` block of the provider sets `allow_network` " +
+							"to `false`.",
+						Optional: true,
+					},
+				},
+			},
+
+			"limits": schema.SingleNestedBlock{
+				Description: "Resource limits applied to the program and the processes it starts, which is only " +
+					"supported on Linux. When a limit terminates the program, the error names the exceeded limit. " +
+					"Limits cannot exceed the limits of the provider. Cannot be combined with `plugin`, `endpoint` or `persistent`.",
+				Attributes: map[string]schema.Attribute{
+					"cpu_seconds": schema.Int64Attribute{
+						Description: "The CPU time the program can use, in seconds.",
+						Optional:    true,
+						Validators:  []validator.Int64{int64validator.AtLeast(1)},
+					},
+
+					"memory_bytes": schema.Int64Attribute{
+						Description: "The size of the virtual address space of the program, in bytes. Allocations " +
+							"beyond the limit fail, which usually terminates the program.",
+						Optional:   true,
+						Validators: []validator.Int64{int64validator.AtLeast(1)},
+					},
+
+					"open_files": schema.Int64Attribute{
+						Description: "The number of files the program can open at once.",
+						Optional:    true,
+						Validators:  []validator.Int64{int64validator.AtLeast(1)},
+					},
+
+					"processes": schema.Int64Attribute{
+						Description: "The number of processes the user of the provider can run, including processes " +
+							"outside of the program.",
+						Optional:   true,
+						Validators: []validator.Int64{int64validator.AtLeast(1)},
+					},
+
+					"output_file_bytes": schema.Int64Attribute{
+						Description: "The size of the files the program can write, in bytes.",
+						Optional:    true,
+						Validators:  []validator.Int64{int64validator.AtLeast(1)},
+					},
+				},
+			},
+		},
 	}
 }
 
-func (n *externalDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+func (n *externalDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*externalProviderData)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *externalProviderData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	n.providerData = providerData
+}
+
+func (n *externalDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("program"),
+			path.MatchRoot("profile"),
+			path.MatchRoot("command"),
+			path.MatchRoot("script"),
+			path.MatchRoot("plugin"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("endpoint"),
+			path.MatchRoot("working_dir"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("persistent"),
+			path.MatchRoot("command"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("persistent"),
+			path.MatchRoot("script"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("persistent"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("persistent"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("output_format"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("output_format"),
+			path.MatchRoot("persistent"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("raw_output"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("raw_output"),
+			path.MatchRoot("persistent"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("raw_output"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("raw_output"),
+			path.MatchRoot("output_format"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("sandbox"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("sandbox"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("allowed_read_paths"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("allowed_read_paths"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("allowed_write_paths"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("allowed_write_paths"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("seccomp_profile"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("seccomp_profile"),
+			path.MatchRoot("endpoint"),
+		),
+		// A denied system call terminates a persistent program for all of
+		// the reads it serves, rather than failing the read which made it.
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("seccomp_profile"),
+			path.MatchRoot("persistent"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("limits"),
+			path.MatchRoot("plugin"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("program_sha256"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("program_signature"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("limits"),
+			path.MatchRoot("endpoint"),
+		),
+		// Limits of a persistent program would apply to all of the reads it
+		// serves, such as the CPU time of the whole operation.
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("limits"),
+			path.MatchRoot("persistent"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("run_as_user"),
+			path.MatchRoot("endpoint"),
+		),
+		datasourcevalidator.Conflicting(
+			path.MatchRoot("run_as_group"),
+			path.MatchRoot("endpoint"),
+		),
+	}
+}
+
+func (n *externalDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
 	var config externalDataSourceModelV0
 
 	diags := req.Config.Get(ctx, &config)
@@ -101,33 +524,205 @@ func (n *externalDataSource) Read(ctx context.Context, req datasource.ReadReques
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	var program []types.String
 
-	diags = config.Program.ElementsAs(ctx, &program, false)
+	if !config.Timeout.IsNull() && !config.Timeout.IsUnknown() {
+		if _, err := parseTimeout(config.Timeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Invalid Timeout",
+				err.Error()+fmt.Sprintf("\n\nTimeout: %s", config.Timeout.ValueString()),
+			)
+		}
+	}
+
+	if !config.Endpoint.IsNull() && !config.Endpoint.IsUnknown() {
+		if _, err := parseEndpoint(config.Endpoint.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("endpoint"),
+				"Invalid External Endpoint",
+				"The endpoint must be a HTTP URL of a loopback address, such as \"http://127.0.0.1:8080/query\", "+
+					"or the path of a Unix domain socket, such as \"unix:///run/helper.sock\"."+
+					fmt.Sprintf("\n\nEndpoint: %s", config.Endpoint.ValueString())+
+					fmt.Sprintf("\nError: %s", err),
+			)
+		}
+	}
+
+	resp.Diagnostics.Append(validateQueryDelivery(ctx, config)...)
+
+	resp.Diagnostics.Append(validateProgramLookup(ctx, config.Program, path.Root("program"))...)
+	resp.Diagnostics.Append(validateProgramLookup(ctx, config.Interpreter, path.Root("interpreter"))...)
+	resp.Diagnostics.Append(validateProgramLookup(ctx, config.Plugin, path.Root("plugin"))...)
+
+	if config.Sandbox != nil && !sandboxSupported {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("sandbox"),
+			"Unsupported Sandbox",
+			"Programs can only be run in a sandbox on Linux. Remove the sandbox block to run the program on this platform."+
+				fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+		)
+	}
+}
+
+func (n *externalDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config externalDataSourceModelV0
+
+	diags := req.Config.Get(ctx, &config)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	filteredProgram := make([]string, 0, len(program))
+	invocation := programInvocation{
+		Query:         make(map[string]string),
+		AttributePath: path.Root("program"),
+		TimeoutPath:   path.Root("timeout"),
+	}
 
-	for _, programArgRaw := range program {
-		if programArgRaw.IsNull() || programArgRaw.ValueString() == "" {
-			continue
+	if !config.Profile.IsNull() {
+		profile, ok := n.providerData.Profiles[config.Profile.ValueString()]
+
+		if !ok {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("profile"),
+				"External Program Profile Not Found",
+				"The data source references a profile which is not declared in the provider configuration. "+
+					"Verify the profile name and the 'profiles' attribute of the provider configuration."+
+					fmt.Sprintf("\n\nProfile: %s", config.Profile.ValueString()),
+			)
+			return
 		}
 
-		filteredProgram = append(filteredProgram, programArgRaw.ValueString())
+		invocation.Program = profile.Program
+		invocation.WorkingDir = profile.WorkingDir
+		invocation.Environment = profile.Environment
+		invocation.Timeout = profile.Timeout
+		invocation.AttributePath = path.Root("profile")
+		invocation.TimeoutPath = path.Root("profile")
+
+		for key, value := range profile.Query {
+			invocation.Query[key] = value
+		}
+	} else if !config.Command.IsNull() {
+		if len(n.providerData.Shell) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("command"),
+				"External Program Shell Not Configured",
+				"The data source was configured with a command, however no shell is available to execute it on this platform. "+
+					"Configure the 'shell' attribute of the provider, for example: [\"bash\", \"-c\"]"+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+			)
+			return
+		}
+
+		invocation.AttributePath = path.Root("command")
+	} else if !config.Script.IsNull() {
+		var interpreter []types.String
+
+		diags = config.Interpreter.ElementsAs(ctx, &interpreter, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		scriptPath, cleanup, err := materializeScript(config.Script.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("script"),
+				"External Script Write Failed",
+				"The data source received an unexpected error while attempting to write the script to a temporary file."+
+					fmt.Sprintf("\n\nError: %s", err),
+			)
+			return
+		}
+
+		defer cleanup()
+
+		invocation.Program = append(filterProgram(interpreter), scriptPath)
+		invocation.AttributePath = path.Root("script")
+	} else if !config.Plugin.IsNull() {
+		var plugin []types.String
+
+		diags = config.Plugin.ElementsAs(ctx, &plugin, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		invocation.Program = filterProgram(plugin)
+		invocation.AttributePath = path.Root("plugin")
+
+		if len(invocation.Program) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("plugin"),
+				"External Program Missing",
+				"The data source was configured without a plugin to execute. Verify the configuration contains at least one non-empty value.",
+			)
+			return
+		}
+	} else if !config.Endpoint.IsNull() {
+		invocation.Endpoint = config.Endpoint.ValueString()
+		invocation.AttributePath = path.Root("endpoint")
+	} else {
+		var program []types.String
+
+		diags = config.Program.ElementsAs(ctx, &program, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		invocation.Program = filterProgram(program)
+
+		if len(invocation.Program) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("program"),
+				"External Program Missing",
+				"The data source was configured without a program to execute. Verify the configuration contains at least one non-empty value.",
+			)
+			return
+		}
 	}
 
-	if len(filteredProgram) == 0 {
+	if !config.WorkingDir.IsNull() {
+		invocation.WorkingDir = config.WorkingDir.ValueString()
+	}
+
+	invocation.QueryDelivery = config.QueryDelivery.ValueString()
+	invocation.ProgramSHA256 = config.ProgramSHA256.ValueString()
+	invocation.ProgramDigests = n.providerData.ProgramDigests
+	invocation.EnvironmentScrubber = n.providerData.EnvironmentScrubber
+	invocation.ProgramSignature = config.ProgramSignature.ValueString()
+	invocation.TrustedKeys = n.providerData.TrustedKeys
+
+	// Endpoints are not executed by the provider.
+	invocation.SignatureRequired = n.providerData.SignatureRequired && config.Endpoint.IsNull()
+
+	if invocation.ProgramSignature != "" && n.providerData.TrustedKeys == nil {
 		resp.Diagnostics.AddAttributeError(
-			path.Root("program"),
-			"External Program Missing",
-			"The data source was configured without a program to execute. Verify the configuration contains at least one non-empty value.",
+			path.Root("program_signature"),
+			"Missing Trusted Keys",
+			"The data source was configured with a program signature, however no trusted keys are configured to verify it. "+
+				"Configure the 'trusted_keys' attribute of the 'program_signatures' block of the provider.",
 		)
 		return
 	}
 
+	if !config.Timeout.IsNull() {
+		timeout, err := parseTimeout(config.Timeout.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Invalid Timeout",
+				err.Error()+fmt.Sprintf("\n\nTimeout: %s", config.Timeout.ValueString()),
+			)
+			return
+		}
+
+		invocation.Timeout = timeout
+		invocation.TimeoutPath = path.Root("timeout")
+	}
+
 	var query map[string]types.String
 
 	diags = config.Query.ElementsAs(ctx, &query, false)
@@ -136,135 +731,154 @@ func (n *externalDataSource) Read(ctx context.Context, req datasource.ReadReques
 		return
 	}
 
-	filteredQuery := make(map[string]string)
-	for key, value := range query {
-		// Preserve v2.2.3 and earlier behavior of filtering whole map elements
-		// with null values.
-		// Reference: https://github.com/hashicorp/terraform-provider-external/issues/208
-		//
-		// The external program protocol could be updated to support null values
-		// as a breaking change by marshaling map[string]*string to JSON.
-		// Reference: https://github.com/hashicorp/terraform-provider-external/issues/209
-		if value.IsNull() {
-			continue
-		}
-
-		filteredQuery[key] = value.ValueString()
+	for key, value := range filterQuery(query) {
+		invocation.Query[key] = value
 	}
 
-	queryJson, err := json.Marshal(filteredQuery)
-	if err != nil {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("query"),
-			"Query Handling Failed",
-			"The data source received an unexpected error while attempting to parse the query. "+
-				"This is always a bug in the external provider code and should be reported to the provider developers."+
-				fmt.Sprintf("\n\nError: %s", err),
-		)
+	var sensitiveKeys []string
+
+	diags = config.SensitiveQueryKeys.ElementsAs(ctx, &sensitiveKeys, false)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// first element is assumed to be an executable command, possibly found
-	// using the PATH environment variable.
-	_, err = exec.LookPath(filteredProgram[0])
+	redactor := newRedactor(invocation.Query, sensitiveKeys, n.providerData.RedactionPatterns)
+	ctx = redactor.mask(ctx)
+	invocation.Redactor = redactor
 
-	// This is a workaround to preserve pre-existing behaviour prior to the upgrade to Go 1.19.
-	// Reference: https://github.com/hashicorp/terraform-provider-external/pull/192
-	//
-	// This workaround will be removed once a warning is being issued to notify practitioners
-	// of a change in behaviour.
-	// Reference: https://github.com/hashicorp/terraform-provider-external/issues/197
-	if errors.Is(err, exec.ErrDot) {
-		err = nil
+	defer func() {
+		resp.Diagnostics = redactor.redactDiagnostics(resp.Diagnostics)
+	}()
+
+	if !config.Command.IsNull() {
+		invocation.Program = shellCommand(n.providerData.Shell, config.Command.ValueString(), invocation.Query)
+		invocation.PositionalValues = len(invocation.Query)
 	}
 
-	if err != nil {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("program"),
-			"External Program Lookup Failed",
-			"The data source received an unexpected error while attempting to parse the query. "+
-				`The data source received an unexpected error while attempting to find the program.
+	invocation.Sandbox, diags = n.sandbox(ctx, config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-The program must be accessible according to the platform where Terraform is running.
+	// The script must remain readable, even though it is within /tmp.
+	if invocation.Sandbox != nil && !config.Script.IsNull() {
+		invocation.Sandbox.ReadOnlyPaths = append(invocation.Sandbox.ReadOnlyPaths, filepath.Dir(invocation.Program[len(invocation.Program)-1]))
+	}
 
-If the expected program should be automatically found on the platform where Terraform is running, ensure that the program is in an expected directory. On Unix-based platforms, these directories are typically searched based on the '$PATH' environment variable. On Windows-based platforms, these directories are typically searched based on the '%PATH%' environment variable.
+	// Endpoints are not executed by the provider.
+	if invocation.Endpoint == "" {
+		runAsUser, runAsGroup := n.providerData.RunAsUser, n.providerData.RunAsGroup
 
-If the expected program is relative to the Terraform configuration, it is recommended that the program name includes the interpolated value of 'path.module' before the program name to ensure that it is compatible with varying module usage. For example: "${path.module}/my-program"
+		if !config.RunAsUser.IsNull() {
+			runAsUser = config.RunAsUser.ValueString()
+		}
 
-The program must also be executable according to the platform where Terraform is running. On Unix-based platforms, the file on the filesystem must have the executable bit set. On Windows-based platforms, no action is typically necessary.
-`+
-				fmt.Sprintf("\nPlatform: %s", runtime.GOOS)+
-				fmt.Sprintf("\nProgram: %s", program[0])+
-				fmt.Sprintf("\nError: %s", err),
-		)
-		return
-	}
+		if !config.RunAsGroup.IsNull() {
+			runAsGroup = config.RunAsGroup.ValueString()
+		}
 
-	workingDir := config.WorkingDir.ValueString()
+		invocation.Credential, diags = runAsCredential(runAsUser, runAsGroup, path.Root("run_as_user"), path.Root("run_as_group"))
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 
-	cmd := exec.CommandContext(ctx, filteredProgram[0], filteredProgram[1:]...)
+	if invocation.Credential != nil {
+		if invocation.Sandbox != nil {
+			invocation.Sandbox.Credential = invocation.Credential
+		}
 
-	// This is a workaround to preserve pre-existing behaviour prior to the upgrade to Go 1.19.
-	// Reference: https://github.com/hashicorp/terraform-provider-external/pull/192
-	//
-	// This workaround will be removed once a warning is being issued to notify practitioners
-	// of a change in behaviour.
-	// Reference: https://github.com/hashicorp/terraform-provider-external/issues/197
-	if errors.Is(cmd.Err, exec.ErrDot) {
-		cmd.Err = nil
+		if !config.Script.IsNull() {
+			if err := shareScript(invocation.Program[len(invocation.Program)-1], invocation.Credential); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("script"),
+					"External Script Write Failed",
+					"The data source received an unexpected error while attempting to make the script readable by the user of the program."+
+						fmt.Sprintf("\n\nUser: %d", invocation.Credential.Uid)+
+						fmt.Sprintf("\nError: %s", err),
+				)
+				return
+			}
+		}
 	}
 
-	cmd.Dir = workingDir
-	cmd.Stdin = bytes.NewReader(queryJson)
+	// Endpoints are not executed by the provider.
+	if len(n.providerData.ExecutionPolicy) > 0 && invocation.Endpoint == "" {
+		resp.Diagnostics.Append(invocation.checkPolicy(ctx, n.providerData.ExecutionPolicy)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
 
 	var stderr strings.Builder
-	cmd.Stderr = &stderr
-
-	tflog.Trace(ctx, "Executing external program", map[string]interface{}{"program": cmd.String()})
 
-	resultJson, err := cmd.Output()
+	if config.RawOutput.ValueBool() {
+		invocation.Stderr = &stderr
+	}
 
-	stderrStr := stderr.String()
+	var resultJson []byte
+	var location string
 
-	tflog.Trace(ctx, "Executed external program", map[string]interface{}{"program": cmd.String(), "output": string(resultJson), "stderr": stderrStr})
+	if invocation.Endpoint != "" {
+		resultJson, diags = n.runEndpoint(ctx, invocation)
+		location = fmt.Sprintf("Endpoint: %s", invocation.Endpoint)
+	} else {
+		var programPath string
 
-	if err != nil {
-		if len(stderrStr) > 0 {
-			resp.Diagnostics.AddAttributeError(
-				path.Root("program"),
-				"External Program Execution Failed",
-				"The data source received an unexpected error while attempting to execute the program."+
-					fmt.Sprintf("\n\nProgram: %s", cmd.Path)+
-					fmt.Sprintf("\nError Message: %s", stderrStr)+
-					fmt.Sprintf("\nState: %s", err),
-			)
-			return
+		if !config.Plugin.IsNull() {
+			resultJson, programPath, diags = n.runPlugin(ctx, invocation)
+		} else if config.Persistent.ValueBool() {
+			resultJson, programPath, diags = n.runWorker(ctx, invocation)
+		} else {
+			resultJson, programPath, diags = n.runProgram(ctx, invocation)
 		}
 
-		resp.Diagnostics.AddAttributeError(
-			path.Root("program"),
-			"External Program Execution Failed",
-			"The data source received an unexpected error while attempting to execute the program.\n\n"+
-				"The program was executed, however it returned no additional error messaging."+
-				fmt.Sprintf("\n\nProgram: %s", cmd.Path)+
-				fmt.Sprintf("\nState: %s", err),
-		)
+		location = fmt.Sprintf("Program: %s", programPath)
+	}
+
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.Stdout = types.StringNull()
+	config.StdoutBase64 = types.StringNull()
+	config.Stderr = types.StringNull()
+
+	if config.RawOutput.ValueBool() {
+		config.Result = types.MapNull(types.StringType)
+		config.Stdout = types.StringValue(strings.ToValidUTF8(string(resultJson), "\uFFFD"))
+		config.StdoutBase64 = types.StringValue(base64.StdEncoding.EncodeToString(resultJson))
+		config.Stderr = types.StringValue(strings.ToValidUTF8(stderr.String(), "\uFFFD"))
+		config.ID = types.StringValue("-")
+
+		diags = resp.State.Set(ctx, config)
+		resp.Diagnostics.Append(diags...)
 		return
 	}
 
-	result := map[string]string{}
-	err = json.Unmarshal(resultJson, &result)
+	outputFormat := config.OutputFormat.ValueString()
+
+	if outputFormat == "" {
+		outputFormat = outputFormatJSON
+	}
+
+	result, err := decodeResult(outputFormat, resultJson)
 	if err != nil {
 		resp.Diagnostics.AddAttributeError(
-			path.Root("program"),
+			invocation.AttributePath,
 			"Unexpected External Program Results",
 			`The data source received unexpected results after executing the program.
 
-Program output must be a JSON encoded map of string keys and string values.
+`+outputFormatDescriptions[outputFormat]+`
 
 If the error is unclear, the output can be viewed by enabling Terraform's logging at TRACE level. Terraform documentation on logging: https://www.terraform.io/internals/debugging
 `+
-				fmt.Sprintf("\nProgram: %s", cmd.Path)+
+				fmt.Sprintf("\n%s", location)+
+				fmt.Sprintf("\nOutput Format: %s", outputFormat)+
 				fmt.Sprintf("\nResult Error: %s", err),
 		)
 		return
@@ -283,9 +897,224 @@ If the error is unclear, the output can be viewed by enabling Terraform's loggin
 }
 
 type externalDataSourceModelV0 struct {
-	Program    types.List   `tfsdk:"program"`
-	WorkingDir types.String `tfsdk:"working_dir"`
-	Query      types.Map    `tfsdk:"query"`
-	Result     types.Map    `tfsdk:"result"`
-	ID         types.String `tfsdk:"id"`
+	Program       types.List   `tfsdk:"program"`
+	Profile       types.String `tfsdk:"profile"`
+	Command       types.String `tfsdk:"command"`
+	Script        types.String `tfsdk:"script"`
+	Interpreter   types.List   `tfsdk:"interpreter"`
+	Plugin        types.List   `tfsdk:"plugin"`
+	Endpoint      types.String `tfsdk:"endpoint"`
+	WorkingDir    types.String `tfsdk:"working_dir"`
+	Timeout       types.String `tfsdk:"timeout"`
+	Persistent    types.Bool   `tfsdk:"persistent"`
+	QueryDelivery types.String `tfsdk:"query_delivery"`
+	OutputFormat  types.String `tfsdk:"output_format"`
+	RawOutput     types.Bool   `tfsdk:"raw_output"`
+
+	AllowedReadPaths  types.List   `tfsdk:"allowed_read_paths"`
+	AllowedWritePaths types.List   `tfsdk:"allowed_write_paths"`
+	SeccompProfile    types.String `tfsdk:"seccomp_profile"`
+	ProgramSHA256     types.String `tfsdk:"program_sha256"`
+	ProgramSignature  types.String `tfsdk:"program_signature"`
+	RunAsUser         types.String `tfsdk:"run_as_user"`
+	RunAsGroup        types.String `tfsdk:"run_as_group"`
+
+	Query              types.Map    `tfsdk:"query"`
+	SensitiveQueryKeys types.List   `tfsdk:"sensitive_query_keys"`
+	Result             types.Map    `tfsdk:"result"`
+	Stdout             types.String `tfsdk:"stdout"`
+	StdoutBase64       types.String `tfsdk:"stdout_base64"`
+	Stderr             types.String `tfsdk:"stderr"`
+	ID                 types.String `tfsdk:"id"`
+
+	Sandbox *externalSandboxModel `tfsdk:"sandbox"`
+	Limits  *externalLimitsModel  `tfsdk:"limits"`
+}
+
+// validateQueryDelivery verifies the query delivery is supported by the
+// configured mode of the data source, and that the placeholders of the
+// program arguments can be parsed when the query is delivered as arguments.
+func validateQueryDelivery(ctx context.Context, config externalDataSourceModelV0) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.QueryDelivery.IsNull() || config.QueryDelivery.IsUnknown() || config.QueryDelivery.ValueString() == queryDeliveryStdin {
+		return diags
+	}
+
+	unsupported := map[string]bool{
+		"command":    !config.Command.IsNull(),
+		"plugin":     !config.Plugin.IsNull(),
+		"endpoint":   !config.Endpoint.IsNull(),
+		"persistent": config.Persistent.ValueBool(),
+	}
+
+	for _, name := range []string{"command", "plugin", "endpoint", "persistent"} {
+		if unsupported[name] {
+			diags.AddAttributeError(
+				path.Root("query_delivery"),
+				"Unsupported Query Delivery",
+				fmt.Sprintf("The query can only be delivered using %q when %q is configured.", queryDeliveryStdin, name)+
+					fmt.Sprintf("\n\nQuery Delivery: %s", config.QueryDelivery.ValueString()),
+			)
+		}
+	}
+
+	if config.QueryDelivery.ValueString() != queryDeliveryArguments || config.Program.IsNull() || config.Program.IsUnknown() {
+		return diags
+	}
+
+	var program []types.String
+
+	diags.Append(config.Program.ElementsAs(ctx, &program, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	for i, argument := range program {
+		if i == 0 || argument.IsNull() || argument.IsUnknown() {
+			continue
+		}
+
+		if _, err := parseArgumentTemplate(argument.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("program").AtListIndex(i),
+				"Invalid Program Argument Template",
+				"The program argument contains an invalid placeholder. Placeholders must be in the form {{ .key }}."+
+					fmt.Sprintf("\n\nError: %s", err),
+			)
+		}
+	}
+
+	return diags
+}
+
+// validateProgramLookup warns if the first element of the list attribute,
+// which is a program to execute, is resolved relative to the current
+// directory.
+func validateProgramLookup(ctx context.Context, programList types.List, programPath path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if programList.IsNull() || programList.IsUnknown() {
+		return diags
+	}
+
+	var program []types.String
+
+	diags.Append(programList.ElementsAs(ctx, &program, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	if len(program) == 0 || program[0].IsNull() || program[0].IsUnknown() {
+		return diags
+	}
+
+	if programLookupRelativeToCurrentDir(program[0].ValueString()) {
+		diags.AddAttributeWarning(
+			programPath.AtListIndex(0),
+			"External Program Resolved Relative to Current Directory",
+			programLookupRelativeToCurrentDirDetail(program[0].ValueString()),
+		)
+	}
+
+	return diags
+}
+
+// parseTimeout parses a timeout, which must be a positive duration.
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+
+	if err != nil || timeout <= 0 {
+		return 0, errors.New("The timeout must be a positive duration, such as \"30s\" or \"5m\".")
+	}
+
+	return timeout, nil
+}
+
+// filterProgram returns the program elements with null and empty values
+// removed.
+func filterProgram(program []types.String) []string {
+	filteredProgram := make([]string, 0, len(program))
+
+	for _, programArgRaw := range program {
+		if programArgRaw.IsNull() || programArgRaw.ValueString() == "" {
+			continue
+		}
+
+		filteredProgram = append(filteredProgram, programArgRaw.ValueString())
+	}
+
+	return filteredProgram
+}
+
+// filterQuery returns the query elements with null values removed.
+func filterQuery(query map[string]types.String) map[string]string {
+	filteredQuery := make(map[string]string)
+
+	for key, value := range query {
+		// Preserve v2.2.3 and earlier behavior of filtering whole map elements
+		// with null values.
+		// Reference: https://github.com/hashicorp/terraform-provider-external/issues/208
+		//
+		// The external program protocol could be updated to support null values
+		// as a breaking change by marshaling map[string]*string to JSON.
+		// Reference: https://github.com/hashicorp/terraform-provider-external/issues/209
+		if value.IsNull() {
+			continue
+		}
+
+		filteredQuery[key] = value.ValueString()
+	}
+
+	return filteredQuery
+}
+
+// shellCommand returns the program which executes the command through the
+// shell. Following the convention of POSIX shells, the first argument after
+// the command becomes the name of the shell script ($0) and the values of the
+// query, ordered by their keys, become the positional parameters.
+func shellCommand(shell []string, command string, query map[string]string) []string {
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	program := make([]string, 0, len(shell)+2+len(keys))
+	program = append(program, shell...)
+	program = append(program, command, "external")
+
+	for _, key := range keys {
+		program = append(program, query[key])
+	}
+
+	return program
+}
+
+// programLookupRelativeToCurrentDir returns true if the given program name
+// would only be found relative to the current directory.
+func programLookupRelativeToCurrentDir(name string) bool {
+	// Only bare program names are looked up using the PATH environment
+	// variable, names containing a path separator are always used as given.
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+
+	_, err := exec.LookPath(name)
+
+	return errors.Is(err, exec.ErrDot)
+}
+
+func programLookupRelativeToCurrentDirDetail(name string) string {
+	return "The program name can only be found relative to the current directory, either because the current " +
+		"directory is searched implicitly on this platform or because the PATH environment variable " +
+		"contains a relative directory. This lookup behavior is preserved for compatibility, but will " +
+		"cause an error if the provider is configured with 'strict_program_lookup' and may be removed in " +
+		"a future major version.\n\n" +
+		"If the expected program is relative to the Terraform configuration, it is recommended that the " +
+		"program name includes the interpolated value of 'path.module' before the program name to ensure " +
+		"that it is compatible with varying module usage. For example: \"${path.module}/my-program\"" +
+		fmt.Sprintf("\n\nProgram: %s", name)
 }
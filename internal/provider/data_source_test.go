@@ -10,8 +10,10 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
@@ -299,6 +301,448 @@ func TestDataSource_CurrentDir(t *testing.T) {
 	})
 }
 
+func TestDataSource_CurrentDir_StrictProgramLookup(t *testing.T) {
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("cannot create get current working dir: %s", err)
+	}
+
+	tempDir := t.TempDir()
+
+	err = os.Rename(programPath, filepath.Join(tempDir, "tf-acc-external-data-source"))
+	if err != nil {
+		t.Fatalf("cannot move tf-acc-external-data-source from go bin to temp dir: %s", err)
+	}
+
+	tempDirRel, err := filepath.Rel(wd, tempDir)
+	if err != nil {
+		t.Fatalf("could not obtain relative directory: %s", err)
+	}
+
+	p := os.Getenv("PATH")
+	t.Setenv("PATH", fmt.Sprintf("%s:%s", p, tempDirRel))
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "external" {
+						strict_program_lookup = true
+					}
+
+					data "external" "test" {
+						program = [%[1]q]
+				
+						query = {
+							value = "test",
+						}
+					}
+				`, "tf-acc-external-data-source"),
+				ExpectError: regexp.MustCompile(`strict_program_lookup`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Profile(t *testing.T) {
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "external" {
+						profiles = {
+							test = {
+								program = [%[1]q, "cheese"]
+
+								query = {
+									value = "pizza"
+									other = "default"
+								}
+							}
+						}
+					}
+
+					data "external" "test" {
+						profile = "test"
+
+						query = {
+							other = "override"
+						}
+					}
+				`, programPath),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.test", "result.argument", "cheese"),
+					resource.TestCheckResourceAttr("data.external.test", "result.query_value", "pizza"),
+					resource.TestCheckResourceAttr("data.external.test", "result.other", "override"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Profile_NotFound(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						profile = "missing"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`External Program Profile Not Found`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Profile_ProgramConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program = ["test"]
+						profile = "test"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no default shell on Windows")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						command = "printf '{\"first\":\"%s\",\"second\":\"%s\"}' \"$1\" \"$2\" | cat"
+
+						query = {
+							b = "it's $(not) expanded"
+							a = "pizza"
+						}
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.test", "result.first", "pizza"),
+					resource.TestCheckResourceAttr("data.external.test", "result.second", "it's $(not) expanded"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Command_ProgramConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program = ["test"]
+						command = "test"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Script(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "interpreter" {
+						interpreter = ["/bin/sh"]
+						script      = <<-EOT
+							query=$(cat)
+							printf '{"query":"%s"}' "$(printf '%s' "$query" | tr -d '{}"')"
+						EOT
+
+						query = {
+							value = "pizza"
+						}
+					}
+
+					data "external" "directive" {
+						script = <<-EOT
+							#!/bin/sh
+							printf '{"value":"cheese"}'
+						EOT
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.interpreter", "result.query", "value:pizza"),
+					resource.TestCheckResourceAttr("data.external.directive", "result.value", "cheese"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Script_InterpreterWithoutScript(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program     = ["test"]
+						interpreter = ["python3"]
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_RawOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						raw_output = true
+						script     = <<-EOT
+							#!/bin/sh
+							printf 'not json\n\377'
+							printf 'warning' >&2
+						EOT
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.test", "stdout", "not json\n\uFFFD"),
+					resource.TestCheckResourceAttr("data.external.test", "stdout_base64", "bm90IGpzb24K/w=="),
+					resource.TestCheckResourceAttr("data.external.test", "stderr", "warning"),
+					resource.TestCheckNoResourceAttr("data.external.test", "result.%"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_RawOutput_OutputFormatConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program       = ["test"]
+						raw_output    = true
+						output_format = "yaml"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Sandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("this test requires Linux")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						script = <<-EOT
+							#!/bin/sh
+							printf '{"pid":"%s"}' "$$"
+						EOT
+
+						sandbox {}
+					}
+				`,
+				Check: resource.TestCheckResourceAttr("data.external.test", "result.pid", "1"),
+			},
+		},
+	})
+}
+
+func TestDataSource_Sandbox_NetworkDenied(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("this test requires Linux")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					provider "external" {
+						sandbox {
+							required      = true
+							allow_network = false
+						}
+					}
+
+					data "external" "test" {
+						program = ["true"]
+
+						sandbox {
+							network = true
+						}
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Sandbox Network Not Allowed`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Persistent(t *testing.T) {
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					data "external" "first" {
+						program    = [%[1]q, "--persistent"]
+						persistent = true
+
+						query = {
+							value = "pizza"
+						}
+					}
+
+					data "external" "second" {
+						program    = [%[1]q, "--persistent"]
+						persistent = true
+
+						query = {
+							value = data.external.first.result.value
+						}
+					}
+				`, programPath),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.first", "result.value", "pizza"),
+					resource.TestCheckResourceAttr("data.external.second", "result.value", "pizza"),
+					resource.TestCheckResourceAttrPair("data.external.first", "result.pid", "data.external.second", "result.pid"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Persistent_CommandConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						command    = "test"
+						persistent = true
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Persistent_SeccompProfileConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program         = ["test"]
+						persistent      = true
+						seccomp_profile = "no-exec"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestDataSource_Persistent_LimitsConflict(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program    = ["test"]
+						persistent = true
+
+						limits {
+							cpu_seconds = 10
+						}
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestShellCommand(t *testing.T) {
+	t.Parallel()
+
+	got := shellCommand(
+		[]string{"/bin/sh", "-c"},
+		`echo "$1" "$2"`,
+		map[string]string{"b": "second", "a": "first; rm -rf /"},
+	)
+
+	expected := []string{"/bin/sh", "-c", `echo "$1" "$2"`, "external", "first; rm -rf /", "second"}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+}
+
 func TestDataSource_upgrade(t *testing.T) {
 	programPath, err := buildDataSourceTestProgram()
 	if err != nil {
@@ -386,10 +830,14 @@ func TestDataSource_upgrade(t *testing.T) {
 }
 
 func buildDataSourceTestProgram() (string, error) {
-	// We have a simple Go program that we use as a stub for testing.
+	return buildTestProgram("tf-acc-external-data-source")
+}
+
+func buildTestProgram(name string) (string, error) {
+	// We have simple Go programs that we use as stubs for testing.
 	cmd := exec.Command(
 		"go", "install",
-		"github.com/terraform-providers/terraform-provider-external/internal/provider/test-programs/tf-acc-external-data-source",
+		"github.com/terraform-providers/terraform-provider-external/internal/provider/test-programs/"+name,
 	)
 	err := cmd.Run()
 
@@ -403,7 +851,7 @@ func buildDataSourceTestProgram() (string, error) {
 	}
 
 	programPath := path.Join(
-		filepath.SplitList(gopath)[0], "bin", "tf-acc-external-data-source",
+		filepath.SplitList(gopath)[0], "bin", name,
 	)
 	return programPath, nil
 }
@@ -0,0 +1,224 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// endpointTarget is a parsed endpoint of the external data source.
+type endpointTarget struct {
+	// URL is the URL the query is sent to. For Unix domain sockets, the host
+	// of the URL is a placeholder.
+	URL string
+
+	// Socket is the path of the Unix domain socket, if any.
+	Socket string
+}
+
+// parseEndpoint parses an endpoint, which is either a HTTP URL of a loopback
+// address, such as http://127.0.0.1:8080/query, or the path of a Unix domain
+// socket, such as unix:///run/helper.sock.
+func parseEndpoint(endpoint string) (endpointTarget, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpointTarget{}, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		host := u.Hostname()
+
+		if host != "localhost" {
+			ip := net.ParseIP(host)
+
+			if ip == nil || !ip.IsLoopback() {
+				return endpointTarget{}, fmt.Errorf("host %q is not a loopback address", host)
+			}
+		}
+
+		return endpointTarget{URL: u.String()}, nil
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			return endpointTarget{}, errors.New("unix endpoints must be in the form unix:///path/to/socket")
+		}
+
+		return endpointTarget{URL: "http://unix/", Socket: u.Path}, nil
+	default:
+		return endpointTarget{}, fmt.Errorf("unsupported scheme %q, expected http, https or unix", u.Scheme)
+	}
+}
+
+// httpClient returns the client used to send the query to the endpoint.
+// Proxies are never used as the endpoint is always local. Redirects are never
+// followed, as they could send the query to a host which is not local, and
+// connections are not kept alive since the client is only used for one read.
+func (t endpointTarget) httpClient() *http.Client {
+	transport := &http.Transport{
+		Proxy:             nil,
+		DisableKeepAlives: true,
+	}
+
+	if t.Socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", t.Socket)
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// runEndpoint sends the query of the invocation to its endpoint as the body
+// of a POST request and returns the body of the response, which follows the
+// same protocol as the standard output of programs.
+func (n *externalDataSource) runEndpoint(ctx context.Context, invocation programInvocation) ([]byte, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	endpointPath := path.Root("endpoint")
+
+	target, err := parseEndpoint(invocation.Endpoint)
+	if err != nil {
+		diags.AddAttributeError(
+			endpointPath,
+			"Invalid External Endpoint",
+			"The endpoint must be a HTTP URL of a loopback address, such as \"http://127.0.0.1:8080/query\", "+
+				"or the path of a Unix domain socket, such as \"unix:///run/helper.sock\"."+
+				fmt.Sprintf("\n\nEndpoint: %s", invocation.Endpoint)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, diags
+	}
+
+	queryJson, err := json.Marshal(invocation.Query)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("query"),
+			"Query Handling Failed",
+			"The data source received an unexpected error while attempting to parse the query. "+
+				"This is always a bug in the external provider code and should be reported to the provider developers."+
+				fmt.Sprintf("\n\nError: %s", err),
+		)
+		return nil, diags
+	}
+
+	if invocation.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, invocation.Timeout)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(queryJson))
+	if err != nil {
+		diags.AddAttributeError(
+			endpointPath,
+			"Invalid External Endpoint",
+			"The data source received an unexpected error while attempting to create the request for the endpoint."+
+				fmt.Sprintf("\n\nEndpoint: %s", invocation.Endpoint)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, diags
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	tflog.Trace(ctx, "Sending query to external endpoint", map[string]interface{}{"endpoint": invocation.Endpoint})
+
+	start := time.Now()
+	statusCode := -1
+
+	var body []byte
+
+	httpResp, err := target.httpClient().Do(httpReq)
+
+	if err == nil {
+		statusCode = httpResp.StatusCode
+		body, err = io.ReadAll(httpResp.Body)
+
+		if closeErr := httpResp.Body.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	tflog.Trace(ctx, "Received response from external endpoint", map[string]interface{}{"endpoint": invocation.Endpoint, "status": statusCode, "output": string(body)})
+
+	if n.providerData.AuditLog != nil {
+		exitStatus := -1
+
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			exitStatus = 0
+		}
+
+		record := newAuditRecord(start, invocation.Endpoint, nil, "", invocation.Query, exitStatus, body, 0)
+		record.HTTPStatus = statusCode
+
+		diags.Append(n.writeAuditRecord(invocation, record, fmt.Sprintf("Endpoint: %s", invocation.Endpoint))...)
+		if diags.HasError() {
+			return nil, diags
+		}
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			diags.Append(invocation.timeoutDiagnostic(fmt.Sprintf("Endpoint: %s", invocation.Endpoint), err))
+			return nil, diags
+		}
+
+		diags.AddAttributeError(
+			endpointPath,
+			"External Endpoint Request Failed",
+			"The data source received an unexpected error while attempting to send the query to the endpoint."+
+				fmt.Sprintf("\n\nEndpoint: %s", invocation.Endpoint)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, diags
+	}
+
+	if statusCode < 200 || statusCode >= 300 {
+		if len(body) > 0 {
+			diags.AddAttributeError(
+				endpointPath,
+				"External Endpoint Request Failed",
+				"The data source received an unsuccessful response from the endpoint."+
+					fmt.Sprintf("\n\nEndpoint: %s", invocation.Endpoint)+
+					fmt.Sprintf("\nError Message: %s", body)+
+					fmt.Sprintf("\nState: %s", httpResp.Status),
+			)
+			return nil, diags
+		}
+
+		diags.AddAttributeError(
+			endpointPath,
+			"External Endpoint Request Failed",
+			"The data source received an unsuccessful response from the endpoint.\n\n"+
+				"The endpoint responded, however it returned no additional error messaging."+
+				fmt.Sprintf("\n\nEndpoint: %s", invocation.Endpoint)+
+				fmt.Sprintf("\nState: %s", httpResp.Status),
+		)
+		return nil, diags
+	}
+
+	return body, diags
+}
@@ -0,0 +1,220 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		endpoint      string
+		expected      endpointTarget
+		expectedError bool
+	}{
+		"http-loopback-ipv4": {
+			endpoint: "http://127.0.0.1:8080/query",
+			expected: endpointTarget{URL: "http://127.0.0.1:8080/query"},
+		},
+		"http-loopback-ipv6": {
+			endpoint: "http://[::1]:8080/query",
+			expected: endpointTarget{URL: "http://[::1]:8080/query"},
+		},
+		"https-localhost": {
+			endpoint: "https://localhost:8443",
+			expected: endpointTarget{URL: "https://localhost:8443"},
+		},
+		"http-remote": {
+			endpoint:      "http://example.com/query",
+			expectedError: true,
+		},
+		"http-private-address": {
+			endpoint:      "http://10.0.0.1/query",
+			expectedError: true,
+		},
+		"unix": {
+			endpoint: "unix:///run/helper.sock",
+			expected: endpointTarget{URL: "http://unix/", Socket: "/run/helper.sock"},
+		},
+		"unix-relative": {
+			endpoint:      "unix://run/helper.sock",
+			expectedError: true,
+		},
+		"unsupported-scheme": {
+			endpoint:      "ftp://127.0.0.1/query",
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseEndpoint(testCase.endpoint)
+
+			if (err != nil) != testCase.expectedError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
+
+func testEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	var query map[string]string
+
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := query["fail"]; ok {
+		http.Error(w, "I was asked to fail", http.StatusInternalServerError)
+		return
+	}
+
+	if _, ok := query["sleep"]; ok {
+		time.Sleep(time.Second)
+	}
+
+	if location, ok := query["redirect"]; ok {
+		http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"method": r.Method, "value": query["value"]})
+}
+
+func TestRunEndpoint(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(testEndpointHandler))
+	t.Cleanup(server.Close)
+
+	socket := filepath.Join(t.TempDir(), "helper.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix domain sockets unavailable: %s", err)
+	}
+
+	unixServer := &http.Server{Handler: http.HandlerFunc(testEndpointHandler)}
+	t.Cleanup(func() { _ = unixServer.Close() })
+
+	go func() { _ = unixServer.Serve(listener) }()
+
+	var redirected atomic.Bool
+
+	redirectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Store(true)
+		testEndpointHandler(w, r)
+	}))
+	t.Cleanup(redirectServer.Close)
+
+	testCases := map[string]struct {
+		invocation    programInvocation
+		expected      map[string]string
+		expectedError string
+	}{
+		"http": {
+			invocation: programInvocation{
+				Endpoint: server.URL,
+				Query:    map[string]string{"value": "pizza"},
+			},
+			expected: map[string]string{"method": "POST", "value": "pizza"},
+		},
+		"unix": {
+			invocation: programInvocation{
+				Endpoint: "unix://" + socket,
+				Query:    map[string]string{"value": "pizza"},
+			},
+			expected: map[string]string{"method": "POST", "value": "pizza"},
+		},
+		"error-response": {
+			invocation: programInvocation{
+				Endpoint: server.URL,
+				Query:    map[string]string{"fail": "true"},
+			},
+			expectedError: "I was asked to fail",
+		},
+		"redirect": {
+			invocation: programInvocation{
+				Endpoint: server.URL,
+				Query:    map[string]string{"redirect": redirectServer.URL},
+			},
+			expectedError: "307 Temporary Redirect",
+		},
+		"timeout": {
+			invocation: programInvocation{
+				Endpoint:    server.URL,
+				Query:       map[string]string{"sleep": "true"},
+				Timeout:     10 * time.Millisecond,
+				TimeoutPath: path.Root("timeout"),
+			},
+			expectedError: "External Program Timed Out",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			n := NewExternalDataSource().(*externalDataSource)
+
+			body, diags := n.runEndpoint(context.Background(), testCase.invocation)
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() {
+					t.Fatal("expected error, got none")
+				}
+
+				got := fmt.Sprintf("%s: %s", diags.Errors()[0].Summary(), diags.Errors()[0].Detail())
+
+				if !strings.Contains(got, testCase.expectedError) {
+					t.Fatalf("expected error containing %q, got: %s", testCase.expectedError, got)
+				}
+
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			var got map[string]string
+
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("unexpected error decoding %q: %s", body, err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		if redirected.Load() {
+			t.Error("expected redirect not to be followed")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultScrubPatterns are the patterns of the names of environment variables
+// commonly containing credentials, which are removed from the environment of
+// programs unless scrubbing is disabled.
+var defaultScrubPatterns = []string{
+	"AWS_*",
+	"ARM_*",
+	"AZURE_*",
+	"GOOGLE_*",
+	"CLOUDSDK_*",
+	"TF_VAR_*",
+	"TF_TOKEN_*",
+	"*_TOKEN",
+	"*_SECRET",
+	"*_PASSWORD",
+	"*_API_KEY",
+	"*_ACCESS_KEY",
+	"*_PRIVATE_KEY",
+}
+
+type providerScrubEnvironmentModel struct {
+	Enabled  types.Bool `tfsdk:"enabled"`
+	Patterns types.List `tfsdk:"patterns"`
+	Allowed  types.List `tfsdk:"allowed"`
+}
+
+// environmentScrubber removes the variables matching its patterns from the
+// environment inherited by programs. Names are matched case-insensitively,
+// as environment variable names are case-insensitive on Windows.
+type environmentScrubber struct {
+	// Patterns are shell patterns of the names of removed variables, where
+	// * matches any sequence of characters.
+	Patterns []string
+
+	// Allowed are the names of variables which are never removed.
+	Allowed []string
+}
+
+// newEnvironmentScrubber returns a scrubber of the default patterns and the
+// additional patterns.
+func newEnvironmentScrubber(patterns []string, allowed []string) *environmentScrubber {
+	return &environmentScrubber{
+		Patterns: append(append([]string(nil), defaultScrubPatterns...), patterns...),
+		Allowed:  allowed,
+	}
+}
+
+// validateScrubPattern returns an error if the pattern is malformed.
+func validateScrubPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+
+	return err
+}
+
+// scrubs returns whether the variable with the given name is removed.
+func (s *environmentScrubber) scrubs(name string) bool {
+	name = strings.ToUpper(name)
+
+	for _, allowed := range s.Allowed {
+		if strings.ToUpper(allowed) == name {
+			return false
+		}
+	}
+
+	for _, pattern := range s.Patterns {
+		// Patterns are verified when the provider is configured.
+		if matched, _ := path.Match(strings.ToUpper(pattern), name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scrub returns the environment without the scrubbed variables, along with
+// the names of the scrubbed variables.
+func (s *environmentScrubber) scrub(environ []string) ([]string, []string) {
+	environment := make([]string, 0, len(environ))
+
+	var scrubbed []string
+
+	for _, variable := range environ {
+		name, _, _ := strings.Cut(variable, "=")
+
+		if s.scrubs(name) {
+			scrubbed = append(scrubbed, name)
+			continue
+		}
+
+		environment = append(environment, variable)
+	}
+
+	return environment, scrubbed
+}
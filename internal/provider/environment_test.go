@@ -0,0 +1,180 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestEnvironmentScrubber_Scrub(t *testing.T) {
+	t.Parallel()
+
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/user",
+		"AWS_ACCESS_KEY_ID=AKIA",
+		"AWS_REGION=us-east-1",
+		"GITHUB_TOKEN=ghp",
+		"github_token=ghp",
+		"ARM_CLIENT_SECRET=secret",
+		"TF_VAR_password=secret",
+		"VAULT_ADDR=https://vault",
+		"=C:=C:\\",
+	}
+
+	testCases := map[string]struct {
+		scrubber         *environmentScrubber
+		expected         []string
+		expectedScrubbed []string
+	}{
+		"default": {
+			scrubber: &environmentScrubber{Patterns: defaultScrubPatterns},
+			expected: []string{
+				"PATH=/usr/bin",
+				"HOME=/home/user",
+				"VAULT_ADDR=https://vault",
+				"=C:=C:\\",
+			},
+			expectedScrubbed: []string{"AWS_ACCESS_KEY_ID", "AWS_REGION", "GITHUB_TOKEN", "github_token", "ARM_CLIENT_SECRET", "TF_VAR_password"},
+		},
+		"patterns-allowed": {
+			scrubber: &environmentScrubber{
+				Patterns: append(append([]string(nil), defaultScrubPatterns...), "VAULT_*"),
+				Allowed:  []string{"aws_region"},
+			},
+			expected: []string{
+				"PATH=/usr/bin",
+				"HOME=/home/user",
+				"AWS_REGION=us-east-1",
+				"=C:=C:\\",
+			},
+			expectedScrubbed: []string{"AWS_ACCESS_KEY_ID", "GITHUB_TOKEN", "github_token", "ARM_CLIENT_SECRET", "TF_VAR_password", "VAULT_ADDR"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, scrubbed := testCase.scrubber.scrub(environ)
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected environment difference: %s", diff)
+			}
+
+			if diff := cmp.Diff(testCase.expectedScrubbed, scrubbed); diff != "" {
+				t.Errorf("unexpected scrubbed difference: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRunProgram_ScrubEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	t.Setenv("TF_ACC_EXTERNAL_TOKEN", "inherited")
+	t.Setenv("TF_ACC_EXTERNAL_KEPT", "inherited")
+
+	n := NewExternalDataSource().(*externalDataSource)
+
+	invocation := programInvocation{
+		Program: []string{"/bin/sh", "-c", `printf '{"token":"%s","kept":"%s","configured":"%s"}' "$TF_ACC_EXTERNAL_TOKEN" "$TF_ACC_EXTERNAL_KEPT" "$TF_ACC_CONFIGURED_TOKEN"`},
+		Environment: map[string]string{
+			"TF_ACC_CONFIGURED_TOKEN": "configured",
+		},
+		AttributePath:       path.Root("program"),
+		EnvironmentScrubber: n.providerData.EnvironmentScrubber,
+	}
+
+	output, _, diags := n.runProgram(context.Background(), invocation)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	var got map[string]string
+
+	if err := json.Unmarshal(output, &got); err != nil {
+		t.Fatalf("unexpected error decoding %q: %s", output, err)
+	}
+
+	expected := map[string]string{
+		"token":      "",
+		"kept":       "inherited",
+		"configured": "configured",
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+}
+
+func TestDataSource_ScrubEnvironment_InvalidPattern(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					provider "external" {
+						scrub_environment {
+							patterns = ["[INVALID"]
+						}
+					}
+
+					data "external" "test" {
+						program = ["true"]
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Scrub Pattern`),
+			},
+		},
+	})
+}
+
+func TestDataSource_ScrubEnvironment_Disabled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	t.Setenv("TF_ACC_EXTERNAL_TOKEN", "inherited")
+
+	program := `program = ["/bin/sh", "-c", "printf '{\"token\":\"%s\"}' \"$TF_ACC_EXTERNAL_TOKEN\""]`
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						` + program + `
+					}
+				`,
+				Check: resource.TestCheckResourceAttr("data.external.test", "result.token", ""),
+			},
+			{
+				Config: `
+					provider "external" {
+						scrub_environment {
+							enabled = false
+						}
+					}
+
+					data "external" "test" {
+						` + program + `
+					}
+				`,
+				Check: resource.TestCheckResourceAttr("data.external.test", "result.token", "inherited"),
+			},
+		},
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// limitsConfig describes the resource limits applied to a program by the
+// shim before executing it. Zero values are not limited.
+type limitsConfig struct {
+	CPUSeconds      uint64 `json:"cpu_seconds,omitempty"`
+	MemoryBytes     uint64 `json:"memory_bytes,omitempty"`
+	OpenFiles       uint64 `json:"open_files,omitempty"`
+	Processes       uint64 `json:"processes,omitempty"`
+	OutputFileBytes uint64 `json:"output_file_bytes,omitempty"`
+}
+
+type externalLimitsModel struct {
+	CPUSeconds      types.Int64 `tfsdk:"cpu_seconds"`
+	MemoryBytes     types.Int64 `tfsdk:"memory_bytes"`
+	OpenFiles       types.Int64 `tfsdk:"open_files"`
+	Processes       types.Int64 `tfsdk:"processes"`
+	OutputFileBytes types.Int64 `tfsdk:"output_file_bytes"`
+}
+
+// config returns the limits of the block.
+func (m externalLimitsModel) config() *limitsConfig {
+	return &limitsConfig{
+		CPUSeconds:      uint64(m.CPUSeconds.ValueInt64()),
+		MemoryBytes:     uint64(m.MemoryBytes.ValueInt64()),
+		OpenFiles:       uint64(m.OpenFiles.ValueInt64()),
+		Processes:       uint64(m.Processes.ValueInt64()),
+		OutputFileBytes: uint64(m.OutputFileBytes.ValueInt64()),
+	}
+}
+
+// exceededLimit describes the resource limit which terminated a program.
+type exceededLimit struct {
+	// Attribute is the attribute of the limits block.
+	Attribute string
+
+	// Description describes the exceeded limit.
+	Description string
+
+	// Likely is set if the limit was probably, but not certainly, the cause
+	// of the termination.
+	Likely bool
+}
@@ -0,0 +1,84 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// limitsSupported is whether resource limits can be applied on this platform.
+const limitsSupported = true
+
+// setLimits applies the resource limits to the current process, which are
+// inherited by the program it executes.
+func setLimits(config *limitsConfig) error {
+	limits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"cpu_seconds", unix.RLIMIT_CPU, config.CPUSeconds},
+		{"memory_bytes", unix.RLIMIT_AS, config.MemoryBytes},
+		{"open_files", unix.RLIMIT_NOFILE, config.OpenFiles},
+		{"processes", unix.RLIMIT_NPROC, config.Processes},
+		{"output_file_bytes", unix.RLIMIT_FSIZE, config.OutputFileBytes},
+	}
+
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+
+		rlimit := unix.Rlimit{Cur: limit.value, Max: limit.value}
+
+		// The soft CPU limit sends SIGXCPU, which the program can handle,
+		// and the hard limit kills the program a second later.
+		if limit.resource == unix.RLIMIT_CPU {
+			rlimit.Max++
+		}
+
+		if err := unix.Setrlimit(limit.resource, &rlimit); err != nil {
+			return fmt.Errorf("unable to set limit %s to %d, which cannot exceed the limits of the provider: %w", limit.name, limit.value, err)
+		}
+	}
+
+	return nil
+}
+
+// limitExceeded returns the resource limit which terminated the program, if
+// any, based on the signal terminating it.
+func limitExceeded(config *limitsConfig, state *os.ProcessState) *exceededLimit {
+	if state == nil {
+		return nil
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return nil
+	}
+
+	cpuLimit := time.Duration(config.CPUSeconds) * time.Second
+
+	switch signal := status.Signal(); {
+	case config.CPUSeconds > 0 && signal == syscall.SIGXCPU:
+		return &exceededLimit{Attribute: "cpu_seconds", Description: fmt.Sprintf("CPU time limit of %d seconds", config.CPUSeconds)}
+	case config.CPUSeconds > 0 && signal == syscall.SIGKILL && state.UserTime()+state.SystemTime() >= cpuLimit:
+		return &exceededLimit{Attribute: "cpu_seconds", Description: fmt.Sprintf("CPU time limit of %d seconds", config.CPUSeconds)}
+	case config.OutputFileBytes > 0 && signal == syscall.SIGXFSZ:
+		return &exceededLimit{Attribute: "output_file_bytes", Description: fmt.Sprintf("output file size limit of %d bytes", config.OutputFileBytes)}
+	case config.MemoryBytes > 0 && (signal == syscall.SIGSEGV || signal == syscall.SIGBUS || signal == syscall.SIGABRT):
+		// Allocations beyond the limit fail rather than terminate the
+		// program, which commonly aborts or crashes as a result.
+		return &exceededLimit{Attribute: "memory_bytes", Description: fmt.Sprintf("memory limit of %d bytes", config.MemoryBytes), Likely: true}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestRunProgram_Limits(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		limits            *limitsConfig
+		program           []string
+		expected          []*regexp.Regexp
+		expectedAttribute string
+	}{
+		"applied": {
+			limits: &limitsConfig{
+				CPUSeconds:      30,
+				MemoryBytes:     1 << 30,
+				OpenFiles:       64,
+				Processes:       4096,
+				OutputFileBytes: 1 << 20,
+			},
+			program: []string{"/bin/cat", "/proc/self/limits"},
+			expected: []*regexp.Regexp{
+				regexp.MustCompile(`Max cpu time\s+30\s+31\s+seconds`),
+				regexp.MustCompile(`Max address space\s+1073741824\s+1073741824\s+bytes`),
+				regexp.MustCompile(`Max open files\s+64\s+64\s+files`),
+				regexp.MustCompile(`Max processes\s+4096\s+4096\s+processes`),
+				regexp.MustCompile(`Max file size\s+1048576\s+1048576\s+bytes`),
+			},
+		},
+		"cpu_seconds": {
+			limits:            &limitsConfig{CPUSeconds: 1},
+			program:           []string{"/bin/sh", "-c", "while :; do :; done"},
+			expectedAttribute: "cpu_seconds",
+		},
+		"output_file_bytes": {
+			limits:            &limitsConfig{OutputFileBytes: 1024},
+			program:           []string{"/bin/sh", "-c", `exec head -c 4096 /dev/zero >"$1"`, "sh", filepath.Join(t.TempDir(), "output")},
+			expectedAttribute: "output_file_bytes",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:       testCase.program,
+				AttributePath: path.Root("program"),
+				Sandbox:       &sandboxConfig{Limits: testCase.limits},
+			}
+
+			output, _, diags := n.runProgram(context.Background(), invocation)
+
+			if testCase.expectedAttribute != "" {
+				if !diags.HasError() {
+					t.Fatal("expected error, got none")
+				}
+
+				expectedPath := path.Root("limits").AtName(testCase.expectedAttribute)
+
+				for _, d := range diags.Errors() {
+					withPath, ok := d.(interface{ Path() path.Path })
+
+					if d.Summary() != "External Program Resource Limit Exceeded" || !ok || !withPath.Path().Equal(expectedPath) {
+						t.Fatalf("expected resource limit error at %s, got: %v", expectedPath, diags)
+					}
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			for _, expected := range testCase.expected {
+				if !expected.Match(output) {
+					t.Errorf("expected output matching %q, got:\n%s", expected, output)
+				}
+			}
+		})
+	}
+}
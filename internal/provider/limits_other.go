@@ -0,0 +1,17 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !linux
+
+package provider
+
+import (
+	"os"
+)
+
+// limitsSupported is whether resource limits can be applied on this platform.
+const limitsSupported = false
+
+func limitExceeded(config *limitsConfig, state *os.ProcessState) *exceededLimit {
+	return nil
+}
@@ -0,0 +1,390 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/terraform-providers/terraform-provider-external/externalprogram/protocol"
+)
+
+const (
+	outputFormatJSON       = "json"
+	outputFormatYAML       = "yaml"
+	outputFormatTOML       = "toml"
+	outputFormatDotenv     = "dotenv"
+	outputFormatProperties = "properties"
+)
+
+// outputFormats are the supported output formats.
+var outputFormats = []string{
+	outputFormatJSON,
+	outputFormatYAML,
+	outputFormatTOML,
+	outputFormatDotenv,
+	outputFormatProperties,
+}
+
+// outputFormatDescriptions describe the output expected for each format in
+// diagnostics.
+var outputFormatDescriptions = map[string]string{
+	outputFormatJSON:       "Program output must be a JSON encoded map of string keys and string values.",
+	outputFormatYAML:       "Program output must be a YAML mapping of string keys and scalar values.",
+	outputFormatTOML:       "Program output must be a TOML document of keys with string, number, boolean or date values, without tables or arrays.",
+	outputFormatDotenv:     "Program output must be dotenv lines in the form KEY=VALUE, where values may be quoted.",
+	outputFormatProperties: "Program output must be Java properties lines in the form key=value or key: value.",
+}
+
+// decodeResult decodes the output of a program in the given format, which
+// defaults to JSON, into the result of the data source.
+func decodeResult(format string, output []byte) (map[string]string, error) {
+	switch format {
+	case "", outputFormatJSON:
+		return protocol.DecodeResult(output)
+	case outputFormatYAML:
+		return decodeYAMLResult(output)
+	case outputFormatTOML:
+		return decodeTOMLResult(output)
+	case outputFormatDotenv:
+		return decodeDotenvResult(output)
+	case outputFormatProperties:
+		return decodePropertiesResult(output)
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// decodeYAMLResult decodes a YAML mapping. Scalar values are used as written,
+// so 8080 and true result in "8080" and "true".
+func decodeYAMLResult(output []byte) (map[string]string, error) {
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(output, &document); err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+
+	// Empty output is an empty document.
+	if document.Kind == 0 {
+		return result, nil
+	}
+
+	mapping := document.Content[0]
+
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping", mapping.Line)
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+			return nil, fmt.Errorf("line %d: expected a string key", key.Line)
+		}
+
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: value of key %q must be a scalar", value.Line, key.Value)
+		}
+
+		if value.ShortTag() == "!!null" {
+			return nil, fmt.Errorf("line %d: value of key %q must not be null", value.Line, key.Value)
+		}
+
+		if _, ok := result[key.Value]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+		}
+
+		result[key.Value] = value.Value
+	}
+
+	return result, nil
+}
+
+// decodeTOMLResult decodes a TOML document of top-level keys.
+func decodeTOMLResult(output []byte) (map[string]string, error) {
+	var document map[string]interface{}
+
+	if _, err := toml.NewDecoder(bytes.NewReader(output)).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(document))
+
+	for key, value := range document {
+		switch value := value.(type) {
+		case string:
+			result[key] = value
+		case int64:
+			result[key] = strconv.FormatInt(value, 10)
+		case float64:
+			result[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			result[key] = strconv.FormatBool(value)
+		case time.Time:
+			result[key] = formatTOMLTime(value)
+		default:
+			return nil, fmt.Errorf("value of key %q must be a string, number, boolean or date, got %T", key, value)
+		}
+	}
+
+	return result, nil
+}
+
+// formatTOMLTime formats a TOML date or time as written, which the decoder
+// marks with the name of the location of local dates and times.
+func formatTOMLTime(value time.Time) string {
+	switch value.Location().String() {
+	case "date-local":
+		return value.Format(time.DateOnly)
+	case "time-local":
+		return value.Format("15:04:05.999999999")
+	case "datetime-local":
+		return value.Format("2006-01-02T15:04:05.999999999")
+	default:
+		return value.Format(time.RFC3339Nano)
+	}
+}
+
+// decodeDotenvResult decodes dotenv lines in the form KEY=VALUE. Blank lines
+// and lines starting with # are ignored, and an export prefix is allowed.
+// Values may be double quoted, supporting \n, \t, \" and \\ escapes, or
+// single quoted, which are used literally. Unquoted values end at the first
+// # preceded by whitespace.
+func decodeDotenvResult(output []byte) (map[string]string, error) {
+	result := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+
+		key = strings.TrimSpace(key)
+
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNumber, key)
+		}
+
+		value, err := unquoteDotenvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		result[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func unquoteDotenvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		var builder strings.Builder
+
+		for i := 1; i < len(value); i++ {
+			switch c := value[i]; c {
+			case '"':
+				if rest := strings.TrimSpace(value[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return "", errors.New("unexpected content after closing quote")
+				}
+
+				return builder.String(), nil
+			case '\\':
+				i++
+
+				if i == len(value) {
+					return "", errors.New("unterminated escape sequence")
+				}
+
+				switch value[i] {
+				case 'n':
+					builder.WriteByte('\n')
+				case 't':
+					builder.WriteByte('\t')
+				case 'r':
+					builder.WriteByte('\r')
+				default:
+					builder.WriteByte(value[i])
+				}
+			default:
+				builder.WriteByte(c)
+			}
+		}
+
+		return "", errors.New("unterminated double quoted value")
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+
+		if end == -1 {
+			return "", errors.New("unterminated single quoted value")
+		}
+
+		if rest := strings.TrimSpace(value[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", errors.New("unexpected content after closing quote")
+		}
+
+		return value[1 : end+1], nil
+	default:
+		if i := strings.Index(value, " #"); i != -1 {
+			value = value[:i]
+		}
+
+		if i := strings.Index(value, "\t#"); i != -1 {
+			value = value[:i]
+		}
+
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// decodePropertiesResult decodes Java properties. Keys and values are
+// separated by =, : or whitespace, lines starting with # or ! are comments,
+// lines ending with an odd number of backslashes continue on the next line
+// and the escapes \t, \n, \r, \f and \uXXXX are supported.
+func decodePropertiesResult(output []byte) (map[string]string, error) {
+	result := map[string]string{}
+
+	lines := strings.Split(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		for continuesOnNextLine(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		keyEnd := len(line)
+
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+
+			if line[j] == '=' || line[j] == ':' || line[j] == ' ' || line[j] == '\t' || line[j] == '\f' {
+				keyEnd = j
+				break
+			}
+		}
+
+		rawKey := line[:keyEnd]
+		rawValue := strings.TrimLeft(line[keyEnd:], " \t\f")
+
+		if strings.HasPrefix(rawValue, "=") || strings.HasPrefix(rawValue, ":") {
+			rawValue = strings.TrimLeft(rawValue[1:], " \t\f")
+		}
+
+		key, err := unescapeProperty(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		value, err := unescapeProperty(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// continuesOnNextLine returns whether a properties line ends with an odd
+// number of backslashes.
+func continuesOnNextLine(line string) bool {
+	backslashes := 0
+
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		backslashes++
+	}
+
+	return backslashes%2 == 1
+}
+
+func unescapeProperty(value string) (string, error) {
+	var builder strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+
+		if i == len(value) {
+			break
+		}
+
+		switch value[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(value) {
+				return "", errors.New("invalid unicode escape sequence")
+			}
+
+			code, err := strconv.ParseUint(value[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errors.New("invalid unicode escape sequence")
+			}
+
+			builder.WriteRune(rune(code))
+			i += 4
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+
+	if !utf8.ValidString(builder.String()) {
+		return "", errors.New("invalid UTF-8")
+	}
+
+	return builder.String(), nil
+}
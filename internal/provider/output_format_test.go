@@ -0,0 +1,264 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestDataSource_OutputFormat(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "yaml" {
+						output_format = "yaml"
+						script        = <<-EOT
+							#!/bin/sh
+							printf 'name: pizza\nport: 8080\n'
+						EOT
+					}
+
+					data "external" "dotenv" {
+						output_format = "dotenv"
+						script        = <<-EOT
+							#!/bin/sh
+							printf '# generated\nexport NAME="pizza"\nPORT=8080\n'
+						EOT
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.yaml", "result.name", "pizza"),
+					resource.TestCheckResourceAttr("data.external.yaml", "result.port", "8080"),
+					resource.TestCheckResourceAttr("data.external.dotenv", "result.NAME", "pizza"),
+					resource.TestCheckResourceAttr("data.external.dotenv", "result.PORT", "8080"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_OutputFormat_Invalid(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						output_format = "toml"
+						script        = <<-EOT
+							#!/bin/sh
+							printf '[table]\nkey = "value"\n'
+						EOT
+					}
+				`,
+				ExpectError: regexp.MustCompile(`(?s)Program output must be a TOML document.*Output Format: toml`),
+			},
+		},
+	})
+}
+
+func TestDecodeResult(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		format        string
+		output        string
+		expected      map[string]string
+		expectedError string
+	}{
+		"json": {
+			format:   outputFormatJSON,
+			output:   `{"a": "1", "b": "2"}`,
+			expected: map[string]string{"a": "1", "b": "2"},
+		},
+		"json-default": {
+			output:   `{"a": "1"}`,
+			expected: map[string]string{"a": "1"},
+		},
+		"json-non-string": {
+			format:        outputFormatJSON,
+			output:        `{"a": 1}`,
+			expectedError: "cannot unmarshal number",
+		},
+		"yaml": {
+			format: outputFormatYAML,
+			output: "name: pizza\nport: 8080\nenabled: true\nquoted: '007'\nblock: |\n  line\n",
+			expected: map[string]string{
+				"name":    "pizza",
+				"port":    "8080",
+				"enabled": "true",
+				"quoted":  "007",
+				"block":   "line\n",
+			},
+		},
+		"yaml-empty": {
+			format:   outputFormatYAML,
+			output:   "",
+			expected: map[string]string{},
+		},
+		"yaml-not-mapping": {
+			format:        outputFormatYAML,
+			output:        "- a\n- b\n",
+			expectedError: "line 1: expected a mapping",
+		},
+		"yaml-nested": {
+			format:        outputFormatYAML,
+			output:        "a: 1\nb:\n  c: 2\n",
+			expectedError: `line 3: value of key "b" must be a scalar`,
+		},
+		"yaml-null": {
+			format:        outputFormatYAML,
+			output:        "a: ~\n",
+			expectedError: `value of key "a" must not be null`,
+		},
+		"yaml-syntax": {
+			format:        outputFormatYAML,
+			output:        "a: [\n",
+			expectedError: "yaml:",
+		},
+		"toml": {
+			format: outputFormatTOML,
+			output: "name = \"pizza\"\nport = 8080\nratio = 0.5\nenabled = true\nday = 2024-01-02\nat = 2024-01-02T03:04:05Z\n",
+			expected: map[string]string{
+				"name":    "pizza",
+				"port":    "8080",
+				"ratio":   "0.5",
+				"enabled": "true",
+				"day":     "2024-01-02",
+				"at":      "2024-01-02T03:04:05Z",
+			},
+		},
+		"toml-table": {
+			format:        outputFormatTOML,
+			output:        "[table]\nkey = \"value\"\n",
+			expectedError: `value of key "table" must be a string, number, boolean or date`,
+		},
+		"toml-array": {
+			format:        outputFormatTOML,
+			output:        "list = [1, 2]\n",
+			expectedError: `value of key "list" must be a string, number, boolean or date`,
+		},
+		"toml-syntax": {
+			format:        outputFormatTOML,
+			output:        "name = \n",
+			expectedError: "toml:",
+		},
+		"dotenv": {
+			format: outputFormatDotenv,
+			output: strings.Join([]string{
+				"# comment",
+				"",
+				"PLAIN=value",
+				"export EXPORTED=yes",
+				`DOUBLE="line\nbreak \"quoted\""`,
+				`SINGLE='$literal\n'`,
+				"COMMENTED=value # trailing",
+				"EMPTY=",
+				"EQUALS=a=b",
+			}, "\n"),
+			expected: map[string]string{
+				"PLAIN":     "value",
+				"EXPORTED":  "yes",
+				"DOUBLE":    "line\nbreak \"quoted\"",
+				"SINGLE":    `$literal\n`,
+				"COMMENTED": "value",
+				"EMPTY":     "",
+				"EQUALS":    "a=b",
+			},
+		},
+		"dotenv-missing-separator": {
+			format:        outputFormatDotenv,
+			output:        "A=1\nB\n",
+			expectedError: "line 2: expected KEY=VALUE",
+		},
+		"dotenv-unterminated": {
+			format:        outputFormatDotenv,
+			output:        `A="value`,
+			expectedError: "line 1: unterminated double quoted value",
+		},
+		"dotenv-trailing-content": {
+			format:        outputFormatDotenv,
+			output:        `A='value' extra`,
+			expectedError: "line 1: unexpected content after closing quote",
+		},
+		"properties": {
+			format: outputFormatProperties,
+			output: strings.Join([]string{
+				"# comment",
+				"! comment",
+				"equals=value",
+				"colon: value",
+				"space value",
+				"  indented = value with spaces  ",
+				`escaped\ key=tab\tunicodeé`,
+				`continued=first \`,
+				"    second",
+				"empty",
+			}, "\n"),
+			expected: map[string]string{
+				"equals":      "value",
+				"colon":       "value",
+				"space":       "value",
+				"indented":    "value with spaces  ",
+				"escaped key": "tab\tunicodeé",
+				"continued":   "first second",
+				"empty":       "",
+			},
+		},
+		"properties-invalid-unicode": {
+			format:        outputFormatProperties,
+			output:        `key=\u00`,
+			expectedError: "line 1: invalid unicode escape sequence",
+		},
+		"unsupported": {
+			format:        "xml",
+			output:        "<a/>",
+			expectedError: `unsupported output format "xml"`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := decodeResult(testCase.format, []byte(testCase.output))
+
+			if testCase.expectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error, got result: %v", got)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error containing %q, got: %s", testCase.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
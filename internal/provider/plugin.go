@@ -0,0 +1,410 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/terraform-providers/terraform-provider-external/externalplugin"
+)
+
+// pluginPool manages the running helpers of data sources with plugin set,
+// with one helper per distinct program, working directory and environment.
+type pluginPool struct {
+	mu      sync.Mutex
+	closed  bool
+	plugins map[string]*runningPlugin
+
+	// starting deduplicates concurrent starts of the same helper, which run
+	// without holding mu so other helpers are not blocked by the handshake.
+	starting singleflight.Group
+}
+
+// runningPlugin is a helper which completed the handshake.
+type runningPlugin struct {
+	client *plugin.Client
+	helper *externalplugin.Client
+	schema *externalplugin.Schema
+	stderr *tailBuffer
+}
+
+func newPluginPool() *pluginPool {
+	return &pluginPool{
+		plugins: make(map[string]*runningPlugin),
+	}
+}
+
+// Close kills all helpers of the pool.
+func (p *pluginPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	for key, running := range p.plugins {
+		running.client.Kill()
+		delete(p.plugins, key)
+	}
+}
+
+// get returns the helper of the invocation, starting it if it is not running.
+// Reads waiting for the same helper to start share the start, which is bound
+// to the context of the read starting it.
+func (p *pluginPool) get(ctx context.Context, invocation programInvocation) (*runningPlugin, error) {
+	key := workerKey(invocation)
+
+	running, err := p.lookup(ctx, key, invocation)
+	if running != nil || err != nil {
+		return running, err
+	}
+
+	started := p.starting.DoChan(key, func() (interface{}, error) {
+		running, err := startPlugin(ctx, invocation)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if p.closed {
+			running.client.Kill()
+			return nil, errors.New("the provider is stopping")
+		}
+
+		p.plugins[key] = running
+
+		return running, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-started:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		return result.Val.(*runningPlugin), nil
+	}
+}
+
+// lookup returns the running helper of the key, if any, removing it from the
+// pool if it exited.
+func (p *pluginPool) lookup(ctx context.Context, key string, invocation programInvocation) (*runningPlugin, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, errors.New("the provider is stopping")
+	}
+
+	running, ok := p.plugins[key]
+	if !ok {
+		return nil, nil
+	}
+
+	if !running.client.Exited() {
+		return running, nil
+	}
+
+	tflog.Debug(ctx, "Restarting exited external plugin", map[string]interface{}{"program": invocation.Program})
+
+	delete(p.plugins, key)
+
+	return nil, nil
+}
+
+// startPlugin starts the program of the invocation as a helper and retrieves
+// its schema. The helper is killed if the context is done before it started,
+// but outlives the context otherwise.
+func startPlugin(ctx context.Context, invocation programInvocation) (*runningPlugin, error) {
+	stderr := &tailBuffer{limit: workerStderrLimit}
+
+	cmd := invocation.command(context.Background())
+
+	// The plugin inherits the environment of the provider, as other programs
+	// do, since go-plugin only sets the handshake variables otherwise.
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	verified, err := invocation.verifyProgram(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plugin is started by the first call of Client, and no longer reads
+	// the copy of the verified program once it completed the handshake. The
+	// copy of a running program cannot be removed on Windows, where it
+	// remains in the temporary directory.
+	defer verified.Close()
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  externalplugin.Handshake,
+		Plugins:          externalplugin.PluginMap(nil),
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		// Managed clients are killed by StopWorkers when the provider stops.
+		Managed: true,
+		// The environment of the command contains the environment of the
+		// provider, which may have been scrubbed.
+		SkipHostEnv: true,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:        "external-plugin",
+			Output:      stderr,
+			Level:       hclog.Debug,
+			DisableTime: true,
+		}),
+	})
+
+	// Killing the client aborts a pending handshake.
+	stopKill := context.AfterFunc(ctx, client.Kill)
+	defer stopKill()
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("%w\n\n%s", err, stderr.String())
+	}
+
+	raw, err := rpcClient.Dispense(externalplugin.PluginName)
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	helper, ok := raw.(*externalplugin.Client)
+	if !ok {
+		client.Kill()
+		return nil, fmt.Errorf("unexpected plugin client type %T", raw)
+	}
+
+	schema, err := helper.Schema(ctx)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("unable to retrieve schema: %w", err)
+	}
+
+	return &runningPlugin{
+		client: client,
+		helper: helper,
+		schema: schema,
+		stderr: stderr,
+	}, nil
+}
+
+// validatePluginQuery verifies the query against the schema of a helper, if
+// the helper declared one.
+func validatePluginQuery(schema *externalplugin.Schema, query map[string]string, programPath string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if schema == nil {
+		return diags
+	}
+
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := schema.Query[key]; !ok {
+			diags.AddAttributeError(
+				path.Root("query").AtMapKey(key),
+				"Unsupported Query Key",
+				fmt.Sprintf("The plugin does not support the query key %q.", key)+
+					fmt.Sprintf("\n\nPlugin: %s", programPath),
+			)
+		}
+	}
+
+	keys = make([]string, 0, len(schema.Query))
+
+	for key := range schema.Query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := query[key]; !schema.Query[key].Required || ok {
+			continue
+		}
+
+		detail := fmt.Sprintf("The plugin requires the query key %q.", key)
+
+		if description := schema.Query[key].Description; description != "" {
+			detail += "\n\n" + description
+		}
+
+		diags.AddAttributeError(
+			path.Root("query"),
+			"Missing Required Query Key",
+			detail+fmt.Sprintf("\n\nPlugin: %s", programPath),
+		)
+	}
+
+	return diags
+}
+
+// pluginErrorDiagnostic returns the diagnostic of an error returned by a
+// helper. Errors relating to a query key are reported on that key.
+func pluginErrorDiagnostic(attributePath path.Path, programPath string, err *externalplugin.Error) diag.Diagnostic {
+	summary := err.Summary
+
+	if summary == "" {
+		summary = "External Plugin Read Failed"
+	}
+
+	if err.QueryKey != "" {
+		attributePath = path.Root("query").AtMapKey(err.QueryKey)
+	}
+
+	return diag.NewAttributeErrorDiagnostic(
+		attributePath,
+		summary,
+		err.Detail+fmt.Sprintf("\n\nPlugin: %s", programPath),
+	)
+}
+
+// runPlugin sends the query of the invocation to a running helper and returns
+// the JSON encoded result along with the resolved path of the program.
+func (n *externalDataSource) runPlugin(ctx context.Context, invocation programInvocation) ([]byte, string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	diags.Append(n.lookupProgram(invocation)...)
+	if diags.HasError() {
+		return nil, "", diags
+	}
+
+	if invocation.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, invocation.Timeout)
+		defer cancel()
+	}
+
+	plugins := n.providerData.Plugins
+
+	// Without a configured provider, the helper only lives for this read.
+	if plugins == nil {
+		plugins = newPluginPool()
+		defer plugins.Close()
+	}
+
+	programPath := invocation.Program[0]
+
+	tflog.Trace(ctx, "Sending query to external plugin", map[string]interface{}{"program": invocation.Program})
+
+	start := time.Now()
+
+	running, err := plugins.get(ctx, invocation)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			diags.Append(invocation.timeoutDiagnostic(fmt.Sprintf("Plugin: %s", programPath), err))
+			return nil, programPath, diags
+		}
+
+		if digestDiag, ok := invocation.programVerificationDiagnostic(programPath, err); ok {
+			diags.Append(digestDiag)
+			return nil, programPath, diags
+		}
+
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Plugin Start Failed",
+			"The data source received an unexpected error while attempting to start the plugin. "+
+				"Plugins must be Go programs serving a helper with the externalplugin package."+
+				fmt.Sprintf("\n\nPlugin: %s", programPath)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, programPath, diags
+	}
+
+	diags.Append(validatePluginQuery(running.schema, invocation.Query, programPath)...)
+	if diags.HasError() {
+		return nil, programPath, diags
+	}
+
+	// The standard error of the plugin is logged through go-plugin, so the
+	// size includes the log prefixes of its lines.
+	stderrBefore := running.stderr.Written()
+
+	err = running.helper.Validate(ctx, invocation.Query)
+
+	var result map[string]string
+
+	if err == nil {
+		result, err = running.helper.Read(ctx, invocation.Query)
+	}
+
+	var resultJson []byte
+
+	if err == nil {
+		resultJson, err = json.Marshal(result)
+	}
+
+	stderrBytes := running.stderr.Written() - stderrBefore
+
+	tflog.Trace(ctx, "Received response from external plugin", map[string]interface{}{"program": programPath, "output": string(resultJson)})
+
+	if n.providerData.AuditLog != nil {
+		exitStatus := 0
+
+		if err != nil {
+			exitStatus = -1
+		}
+
+		record := newAuditRecord(start, programPath, invocation.Program[1:], invocation.WorkingDir, invocation.Query, exitStatus, resultJson, stderrBytes)
+
+		diags.Append(n.writeAuditRecord(invocation, record, fmt.Sprintf("Plugin: %s", programPath))...)
+		if diags.HasError() {
+			return nil, programPath, diags
+		}
+	}
+
+	if err != nil {
+		var pluginErr *externalplugin.Error
+
+		if errors.As(err, &pluginErr) {
+			diags.Append(pluginErrorDiagnostic(invocation.AttributePath, programPath, pluginErr))
+			return nil, programPath, diags
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			diags.Append(invocation.timeoutDiagnostic(fmt.Sprintf("Plugin: %s", programPath), err))
+			return nil, programPath, diags
+		}
+
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Plugin Read Failed",
+			"The data source received an unexpected error while attempting to send the query to the plugin."+
+				fmt.Sprintf("\n\nPlugin: %s", programPath)+
+				fmt.Sprintf("\nError Message: %s", running.stderr.String())+
+				fmt.Sprintf("\nState: %s", err),
+		)
+		return nil, programPath, diags
+	}
+
+	return resultJson, programPath, diags
+}
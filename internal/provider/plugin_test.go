@@ -0,0 +1,303 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"github.com/terraform-providers/terraform-provider-external/externalplugin"
+)
+
+func TestDataSource_Plugin(t *testing.T) {
+	programPath, err := buildTestProgram("tf-acc-external-plugin")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					data "external" "first" {
+						plugin = [%[1]q]
+
+						query = {
+							value = "pizza"
+						}
+					}
+
+					data "external" "second" {
+						plugin = [%[1]q]
+
+						query = {
+							value = data.external.first.result.value
+						}
+					}
+				`, programPath),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.first", "result.value", "pizza"),
+					resource.TestCheckResourceAttr("data.external.second", "result.value", "pizza"),
+					resource.TestCheckResourceAttrPair("data.external.first", "result.pid", "data.external.second", "result.pid"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Plugin_Error(t *testing.T) {
+	programPath, err := buildTestProgram("tf-acc-external-plugin")
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					data "external" "test" {
+						plugin = [%[1]q]
+
+						query = {
+							value = "invalid"
+						}
+					}
+				`, programPath),
+				ExpectError: regexp.MustCompile(`I was asked to reject the value`),
+			},
+		},
+	})
+}
+
+func TestRunPlugin(t *testing.T) {
+	t.Parallel()
+
+	programPath, err := buildTestProgram("tf-acc-external-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewExternalDataSource().(*externalDataSource)
+	n.providerData.Plugins = newPluginPool()
+	t.Cleanup(n.providerData.Plugins.Close)
+
+	read := func(query map[string]string, timeout time.Duration) (map[string]string, diag.Diagnostics) {
+		invocation := programInvocation{
+			Program:       []string{programPath},
+			Query:         query,
+			Timeout:       timeout,
+			TimeoutPath:   path.Root("timeout"),
+			AttributePath: path.Root("plugin"),
+		}
+
+		resultJson, _, diags := n.runPlugin(context.Background(), invocation)
+
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		var result map[string]string
+
+		if err := json.Unmarshal(resultJson, &result); err != nil {
+			t.Fatalf("unexpected error decoding %q: %s", resultJson, err)
+		}
+
+		return result, diags
+	}
+
+	first, diags := read(map[string]string{"value": "pizza"}, 0)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	second, diags := read(map[string]string{"value": "pasta"}, 0)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if diff := cmp.Diff(map[string]string{"pid": first["pid"], "reads": "2", "value": "pasta"}, second); diff != "" {
+		t.Errorf("expected plugin to be reused: %s", diff)
+	}
+
+	// The plugin inherits the environment of the provider.
+	environment, diags := read(map[string]string{"value": "pizza", "env": "PATH"}, 0)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	if environment["env"] == "" || environment["env"] != os.Getenv("PATH") {
+		t.Errorf("expected plugin to inherit PATH %q, got %q", os.Getenv("PATH"), environment["env"])
+	}
+
+	testCases := map[string]struct {
+		query         map[string]string
+		timeout       time.Duration
+		expectedPath  path.Path
+		expectedError string
+	}{
+		"error": {
+			query:         map[string]string{"value": "pizza", "fail": "true"},
+			expectedPath:  path.Root("plugin"),
+			expectedError: "External Plugin Read Failed: I was asked to fail",
+		},
+		"typed-error": {
+			query:         map[string]string{"value": "invalid"},
+			expectedPath:  path.Root("query").AtMapKey("value"),
+			expectedError: "Invalid Value: I was asked to reject the value.",
+		},
+		"unsupported-key": {
+			query:         map[string]string{"value": "pizza", "other": "true"},
+			expectedPath:  path.Root("query").AtMapKey("other"),
+			expectedError: "Unsupported Query Key",
+		},
+		"missing-key": {
+			query:         map[string]string{},
+			expectedPath:  path.Root("query"),
+			expectedError: "Missing Required Query Key",
+		},
+		"timeout": {
+			query:         map[string]string{"value": "pizza", "sleep": "true"},
+			timeout:       10 * time.Millisecond,
+			expectedPath:  path.Root("timeout"),
+			expectedError: "External Program Timed Out",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := read(testCase.query, testCase.timeout)
+
+			if !diags.HasError() {
+				t.Fatal("expected error, got none")
+			}
+
+			errDiag := diags.Errors()[0]
+			got := fmt.Sprintf("%s: %s", errDiag.Summary(), errDiag.Detail())
+
+			if !strings.Contains(got, testCase.expectedError) {
+				t.Errorf("expected error containing %q, got: %s", testCase.expectedError, got)
+			}
+
+			withPath, ok := errDiag.(diag.DiagnosticWithPath)
+			if !ok || !withPath.Path().Equal(testCase.expectedPath) {
+				t.Errorf("expected error on %s, got: %v", testCase.expectedPath, errDiag)
+			}
+		})
+	}
+}
+
+func TestRunPlugin_HungStart(t *testing.T) {
+	t.Parallel()
+
+	programPath, err := buildTestProgram("tf-acc-external-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewExternalDataSource().(*externalDataSource)
+	n.providerData.Plugins = newPluginPool()
+	t.Cleanup(n.providerData.Plugins.Close)
+
+	hung := make(chan diag.Diagnostics, 1)
+
+	go func() {
+		_, _, diags := n.runPlugin(context.Background(), programInvocation{
+			Program:       []string{programPath, "--hang"},
+			Query:         map[string]string{"value": "pizza"},
+			Timeout:       500 * time.Millisecond,
+			TimeoutPath:   path.Root("timeout"),
+			AttributePath: path.Root("plugin"),
+		})
+
+		hung <- diags
+	}()
+
+	// Other plugins start while the hung plugin is starting.
+	_, _, diags := n.runPlugin(context.Background(), programInvocation{
+		Program:       []string{programPath},
+		Query:         map[string]string{"value": "pizza"},
+		TimeoutPath:   path.Root("timeout"),
+		AttributePath: path.Root("plugin"),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	select {
+	case diags := <-hung:
+		if !diags.HasError() || diags.Errors()[0].Summary() != "External Program Timed Out" {
+			t.Errorf("expected timeout of the hung plugin, got: %v", diags)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the timeout to apply to the start of the plugin")
+	}
+}
+
+func TestValidatePluginQuery(t *testing.T) {
+	t.Parallel()
+
+	schema := &externalplugin.Schema{
+		Query: map[string]externalplugin.QueryKey{
+			"region": {Required: true},
+			"zone":   {},
+		},
+	}
+
+	testCases := map[string]struct {
+		schema   *externalplugin.Schema
+		query    map[string]string
+		expected diag.Diagnostics
+	}{
+		"no-schema": {
+			query: map[string]string{"anything": "goes"},
+		},
+		"valid": {
+			schema: schema,
+			query:  map[string]string{"region": "eu-west-1", "zone": "a"},
+		},
+		"unsupported-and-missing": {
+			schema: schema,
+			query:  map[string]string{"zone": "a", "other": "b"},
+			expected: diag.Diagnostics{
+				diag.NewAttributeErrorDiagnostic(
+					path.Root("query").AtMapKey("other"),
+					"Unsupported Query Key",
+					"The plugin does not support the query key \"other\".\n\nPlugin: helper",
+				),
+				diag.NewAttributeErrorDiagnostic(
+					path.Root("query"),
+					"Missing Required Query Key",
+					"The plugin requires the query key \"region\".\n\nPlugin: helper",
+				),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := validatePluginQuery(testCase.schema, testCase.query, "helper")
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/cel-go/cel"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// policyCostLimit bounds the cost of evaluating a rule of the execution
+// policy, so a rule cannot stall the provider.
+const policyCostLimit = 1_000_000
+
+// policyRule is a named CEL expression of the execution policy, which must
+// evaluate to true for a program to be executed.
+type policyRule struct {
+	Name       string
+	Expression string
+	Program    cel.Program
+}
+
+// executionPolicy is the list of rules a program must satisfy, sorted by
+// name.
+type executionPolicy []policyRule
+
+// policyInput describes the program of an invocation to the rules of the
+// execution policy.
+type policyInput struct {
+	// Path is the absolute path of the program.
+	Path string
+
+	// Args are the arguments of the program as configured, before query
+	// values are substituted.
+	Args []string
+
+	WorkingDir string
+
+	// QueryKeys are the sorted keys of the query.
+	QueryKeys []string
+}
+
+// newPolicyEnvironment returns the CEL environment of the rules of the
+// execution policy. The query is only exposed by key, as its values can be
+// sensitive.
+func newPolicyEnvironment() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("program", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("query", cel.ListType(cel.StringType)),
+	)
+}
+
+// expandExecutionPolicy compiles the rules of the execution_policy attribute
+// of the provider.
+func expandExecutionPolicy(ctx context.Context, value types.Map) (executionPolicy, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if value.IsNull() || value.IsUnknown() {
+		return nil, diags
+	}
+
+	var expressions map[string]string
+
+	diags.Append(value.ElementsAs(ctx, &expressions, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	env, err := newPolicyEnvironment()
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("execution_policy"),
+			"Execution Policy Handling Failed",
+			"The provider received an unexpected error while attempting to create the environment of the execution policy. "+
+				"This is always a bug in the external provider code and should be reported to the provider developers."+
+				fmt.Sprintf("\n\nError: %s", err),
+		)
+		return nil, diags
+	}
+
+	names := make([]string, 0, len(expressions))
+
+	for name := range expressions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	policy := make(executionPolicy, 0, len(names))
+
+	for _, name := range names {
+		expression := expressions[name]
+
+		ast, issues := env.Compile(expression)
+		if issues != nil && issues.Err() != nil {
+			diags.AddAttributeError(
+				path.Root("execution_policy").AtMapKey(name),
+				"Invalid Execution Policy",
+				"The provider was configured with an execution policy rule which is not a valid CEL expression."+
+					fmt.Sprintf("\n\nRule: %s", name)+
+					fmt.Sprintf("\nError: %s", issues.Err()),
+			)
+			continue
+		}
+
+		// Fields of the program are dynamically typed, so their type is only
+		// known when evaluating the rule.
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			diags.AddAttributeError(
+				path.Root("execution_policy").AtMapKey(name),
+				"Invalid Execution Policy",
+				"The provider was configured with an execution policy rule which does not evaluate to a boolean."+
+					fmt.Sprintf("\n\nRule: %s", name)+
+					fmt.Sprintf("\nType: %s", ast.OutputType()),
+			)
+			continue
+		}
+
+		program, err := env.Program(ast, cel.CostLimit(policyCostLimit))
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("execution_policy").AtMapKey(name),
+				"Invalid Execution Policy",
+				"The provider was unable to prepare an execution policy rule for evaluation."+
+					fmt.Sprintf("\n\nRule: %s", name)+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			continue
+		}
+
+		policy = append(policy, policyRule{Name: name, Expression: expression, Program: program})
+	}
+
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	return policy, diags
+}
+
+// evaluate returns the first rule not satisfied by the program, or nil if
+// the program can be executed. The rule is returned along with an error if
+// it cannot be evaluated.
+func (p executionPolicy) evaluate(input policyInput) (*policyRule, error) {
+	args := input.Args
+
+	if args == nil {
+		args = []string{}
+	}
+
+	queryKeys := input.QueryKeys
+
+	if queryKeys == nil {
+		queryKeys = []string{}
+	}
+
+	activation := map[string]interface{}{
+		"program": map[string]interface{}{
+			"path":        input.Path,
+			"args":        args,
+			"working_dir": input.WorkingDir,
+		},
+		"query": queryKeys,
+	}
+
+	for i := range p {
+		rule := &p[i]
+
+		result, _, err := rule.Program.Eval(activation)
+		if err != nil {
+			return rule, err
+		}
+
+		allowed, ok := result.Value().(bool)
+		if !ok {
+			return rule, fmt.Errorf("the rule evaluated to %s instead of a boolean", result.Type().TypeName())
+		}
+
+		if !allowed {
+			return rule, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// checkPolicy evaluates the execution policy against the resolved program of
+// the invocation. Programs which cannot be found are not evaluated, as their
+// execution fails regardless.
+func (i programInvocation) checkPolicy(ctx context.Context, policy executionPolicy) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	cmd := i.command(ctx)
+	if cmd.Err != nil {
+		return diags
+	}
+
+	programPath, err := programFilePath(cmd)
+	if err != nil {
+		programPath = cmd.Path
+	}
+
+	workingDir, err := filepath.Abs(cmd.Dir)
+	if err != nil {
+		workingDir = cmd.Dir
+	}
+
+	queryKeys := make([]string, 0, len(i.Query))
+
+	for key := range i.Query {
+		queryKeys = append(queryKeys, key)
+	}
+
+	sort.Strings(queryKeys)
+
+	// Query values passed as positional parameters of commands are not
+	// arguments of the configuration.
+	rule, err := policy.evaluate(policyInput{
+		Path:       programPath,
+		Args:       i.Program[1 : len(i.Program)-i.PositionalValues],
+		WorkingDir: workingDir,
+		QueryKeys:  queryKeys,
+	})
+
+	if err != nil {
+		diags.AddAttributeError(
+			i.AttributePath,
+			"Execution Policy Evaluation Failed",
+			"A rule of the execution policy of the provider could not be evaluated, so the program was not executed."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nRule: %s", rule.Name)+
+				fmt.Sprintf("\nExpression: %s", rule.Expression)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return diags
+	}
+
+	if rule != nil {
+		diags.AddAttributeError(
+			i.AttributePath,
+			"Execution Policy Violation",
+			"The program does not satisfy a rule of the execution policy of the provider, so it was not executed. "+
+				"Verify the program, its arguments, working directory and query keys against the rule."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nRule: %s", rule.Name)+
+				fmt.Sprintf("\nExpression: %s", rule.Expression),
+		)
+	}
+
+	return diags
+}
@@ -0,0 +1,208 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testExecutionPolicy(t *testing.T, rules map[string]string) executionPolicy {
+	t.Helper()
+
+	elements := make(map[string]attr.Value, len(rules))
+
+	for name, expression := range rules {
+		elements[name] = types.StringValue(expression)
+	}
+
+	policy, diags := expandExecutionPolicy(context.Background(), types.MapValueMust(types.StringType, elements))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	return policy
+}
+
+func TestExpandExecutionPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		expression string
+		summary    string
+	}{
+		"valid": {
+			expression: `program.path.startsWith('/opt/tf-helpers/') && !('password' in query)`,
+		},
+		"syntax": {
+			expression: `program.path.startsWith(`,
+			summary:    "Invalid Execution Policy",
+		},
+		"undeclared": {
+			expression: `environment.size() == 0`,
+			summary:    "Invalid Execution Policy",
+		},
+		"not boolean": {
+			expression: `size(query)`,
+			summary:    "Invalid Execution Policy",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value := types.MapValueMust(types.StringType, map[string]attr.Value{
+				"rule": types.StringValue(testCase.expression),
+			})
+
+			policy, diags := expandExecutionPolicy(context.Background(), value)
+
+			if testCase.summary != "" {
+				if !diags.HasError() || diags.Errors()[0].Summary() != testCase.summary {
+					t.Fatalf("expected %q error, got: %v", testCase.summary, diags)
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if len(policy) != 1 || policy[0].Name != "rule" {
+				t.Errorf("unexpected policy: %v", policy)
+			}
+		})
+	}
+}
+
+func TestExecutionPolicy_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	policy := testExecutionPolicy(t, map[string]string{
+		"helpers":      `program.path.startsWith('/opt/tf-helpers/')`,
+		"no_passwords": `!('password' in query)`,
+		"no_insecure":  `!program.args.exists(arg, arg == '--insecure')`,
+		"working_dir":  `program.working_dir != '/'`,
+	})
+
+	testCases := map[string]struct {
+		input    policyInput
+		expected string
+	}{
+		"allowed": {
+			input: policyInput{
+				Path:       "/opt/tf-helpers/lookup",
+				Args:       []string{"--region", "eu"},
+				WorkingDir: "/work",
+				QueryKeys:  []string{"id"},
+			},
+		},
+		"empty": {
+			input: policyInput{
+				Path:       "/opt/tf-helpers/lookup",
+				WorkingDir: "/work",
+			},
+		},
+		"path": {
+			input: policyInput{
+				Path:       "/usr/bin/curl",
+				WorkingDir: "/work",
+			},
+			expected: "helpers",
+		},
+		"args": {
+			input: policyInput{
+				Path:       "/opt/tf-helpers/lookup",
+				Args:       []string{"--insecure"},
+				WorkingDir: "/work",
+			},
+			expected: "no_insecure",
+		},
+		"query": {
+			input: policyInput{
+				Path:       "/opt/tf-helpers/lookup",
+				WorkingDir: "/work",
+				QueryKeys:  []string{"password", "user"},
+			},
+			expected: "no_passwords",
+		},
+		"working_dir": {
+			input: policyInput{
+				Path:       "/opt/tf-helpers/lookup",
+				WorkingDir: "/",
+			},
+			expected: "working_dir",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rule, err := policy.evaluate(testCase.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var actual string
+
+			if rule != nil {
+				actual = rule.Name
+			}
+
+			if actual != testCase.expected {
+				t.Errorf("expected rule %q, got %q", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExecutionPolicy_EvaluateError(t *testing.T) {
+	t.Parallel()
+
+	policy := testExecutionPolicy(t, map[string]string{
+		"unknown_field": `program.interpreter == 'python3'`,
+		"not_boolean":   `program.path`,
+	})
+
+	for _, expected := range []string{"not_boolean", "unknown_field"} {
+		rule, err := policy.evaluate(policyInput{Path: "/opt/tf-helpers/lookup"})
+		if err == nil {
+			t.Fatalf("expected error for rule %q, got none", expected)
+		}
+
+		if rule.Name != expected {
+			t.Fatalf("expected rule %q, got %q", expected, rule.Name)
+		}
+
+		policy = policy[1:]
+	}
+}
+
+func TestProgramInvocation_CheckPolicy_Command(t *testing.T) {
+	t.Parallel()
+
+	policy := testExecutionPolicy(t, map[string]string{
+		"args": `program.args == ['-c', 'echo "$1"', 'external']`,
+	})
+
+	query := map[string]string{"password": "hunter2"}
+
+	invocation := programInvocation{
+		Program:          shellCommand([]string{"/bin/sh", "-c"}, `echo "$1"`, query),
+		Query:            query,
+		PositionalValues: len(query),
+		AttributePath:    path.Root("command"),
+	}
+
+	diags := invocation.checkPolicy(context.Background(), policy)
+	if diags.HasError() {
+		t.Fatalf("expected query values to be excluded from the arguments, got: %v", diags)
+	}
+}
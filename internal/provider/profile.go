@@ -0,0 +1,132 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// externalProgramProfile is a validated program profile declared in the
+// provider configuration and referenced by data sources by name.
+type externalProgramProfile struct {
+	Program     []string
+	Query       map[string]string
+	Environment map[string]string
+	WorkingDir  string
+	Timeout     time.Duration
+}
+
+type externalProgramProfileModel struct {
+	Program     types.List   `tfsdk:"program"`
+	Query       types.Map    `tfsdk:"query"`
+	Environment types.Map    `tfsdk:"environment"`
+	WorkingDir  types.String `tfsdk:"working_dir"`
+	Timeout     types.String `tfsdk:"timeout"`
+}
+
+// expandProgramProfiles validates the profiles of the provider configuration
+// and converts them into their runtime representation.
+func expandProgramProfiles(ctx context.Context, profiles types.Map) (map[string]externalProgramProfile, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	result := make(map[string]externalProgramProfile)
+
+	if profiles.IsNull() || profiles.IsUnknown() {
+		return result, diags
+	}
+
+	var profileModels map[string]externalProgramProfileModel
+
+	diags.Append(profiles.ElementsAs(ctx, &profileModels, false)...)
+	if diags.HasError() {
+		return result, diags
+	}
+
+	for name, profileModel := range profileModels {
+		profilePath := path.Root("profiles").AtMapKey(name)
+
+		var program []types.String
+
+		diags.Append(profileModel.Program.ElementsAs(ctx, &program, false)...)
+		if diags.HasError() {
+			return result, diags
+		}
+
+		profile := externalProgramProfile{
+			Program:     filterProgram(program),
+			Query:       make(map[string]string),
+			Environment: make(map[string]string),
+			WorkingDir:  profileModel.WorkingDir.ValueString(),
+		}
+
+		if len(profile.Program) == 0 {
+			diags.AddAttributeError(
+				profilePath.AtName("program"),
+				"External Program Missing",
+				fmt.Sprintf("The profile %q was configured without a program to execute. ", name)+
+					"Verify the configuration contains at least one non-empty value.",
+			)
+			continue
+		}
+
+		var query map[string]types.String
+
+		diags.Append(profileModel.Query.ElementsAs(ctx, &query, false)...)
+		if diags.HasError() {
+			return result, diags
+		}
+
+		profile.Query = filterQuery(query)
+
+		var environment map[string]types.String
+
+		diags.Append(profileModel.Environment.ElementsAs(ctx, &environment, false)...)
+		if diags.HasError() {
+			return result, diags
+		}
+
+		for key, value := range environment {
+			if value.IsNull() {
+				continue
+			}
+
+			profile.Environment[key] = value.ValueString()
+		}
+
+		if !profileModel.Timeout.IsNull() && !profileModel.Timeout.IsUnknown() {
+			timeout, err := parseTimeout(profileModel.Timeout.ValueString())
+
+			if err != nil {
+				diags.AddAttributeError(
+					profilePath.AtName("timeout"),
+					"Invalid Timeout",
+					err.Error()+
+						fmt.Sprintf("\n\nProfile: %s", name)+
+						fmt.Sprintf("\nTimeout: %s", profileModel.Timeout.ValueString()),
+				)
+				continue
+			}
+
+			profile.Timeout = timeout
+		}
+
+		if programLookupRelativeToCurrentDir(profile.Program[0]) {
+			diags.AddAttributeWarning(
+				profilePath.AtName("program").AtListIndex(0),
+				"External Program Resolved Relative to Current Directory",
+				programLookupRelativeToCurrentDirDetail(profile.Program[0]),
+			)
+		}
+
+		result[name] = profile
+	}
+
+	return result, diags
+}
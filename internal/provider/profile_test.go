@@ -0,0 +1,142 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var testProgramProfileAttrTypes = map[string]attr.Type{
+	"program":     types.ListType{ElemType: types.StringType},
+	"query":       types.MapType{ElemType: types.StringType},
+	"environment": types.MapType{ElemType: types.StringType},
+	"working_dir": types.StringType,
+	"timeout":     types.StringType,
+}
+
+func testProgramProfiles(t *testing.T, profiles map[string]map[string]attr.Value) types.Map {
+	t.Helper()
+
+	elements := make(map[string]attr.Value, len(profiles))
+
+	for name, attributes := range profiles {
+		values := map[string]attr.Value{
+			"program":     types.ListNull(types.StringType),
+			"query":       types.MapNull(types.StringType),
+			"environment": types.MapNull(types.StringType),
+			"working_dir": types.StringNull(),
+			"timeout":     types.StringNull(),
+		}
+
+		for key, value := range attributes {
+			values[key] = value
+		}
+
+		elements[name] = types.ObjectValueMust(testProgramProfileAttrTypes, values)
+	}
+
+	return types.MapValueMust(types.ObjectType{AttrTypes: testProgramProfileAttrTypes}, elements)
+}
+
+func TestExpandProgramProfiles(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		profiles      types.Map
+		expected      map[string]externalProgramProfile
+		expectedError bool
+	}{
+		"null": {
+			profiles: types.MapNull(types.ObjectType{AttrTypes: testProgramProfileAttrTypes}),
+			expected: map[string]externalProgramProfile{},
+		},
+		"all-attributes": {
+			profiles: testProgramProfiles(t, map[string]map[string]attr.Value{
+				"inventory": {
+					"program": types.ListValueMust(types.StringType, []attr.Value{
+						types.StringValue("/opt/bin/inventory"),
+						types.StringNull(),
+						types.StringValue("--json"),
+					}),
+					"query": types.MapValueMust(types.StringType, map[string]attr.Value{
+						"region": types.StringValue("eu-west-1"),
+						"null":   types.StringNull(),
+					}),
+					"environment": types.MapValueMust(types.StringType, map[string]attr.Value{
+						"INVENTORY_MODE": types.StringValue("read-only"),
+					}),
+					"working_dir": types.StringValue("/tmp"),
+					"timeout":     types.StringValue("30s"),
+				},
+			}),
+			expected: map[string]externalProgramProfile{
+				"inventory": {
+					Program:     []string{"/opt/bin/inventory", "--json"},
+					Query:       map[string]string{"region": "eu-west-1"},
+					Environment: map[string]string{"INVENTORY_MODE": "read-only"},
+					WorkingDir:  "/tmp",
+					Timeout:     30 * time.Second,
+				},
+			},
+		},
+		"empty-program": {
+			profiles: testProgramProfiles(t, map[string]map[string]attr.Value{
+				"inventory": {
+					"program": types.ListValueMust(types.StringType, []attr.Value{
+						types.StringValue(""),
+					}),
+				},
+			}),
+			expectedError: true,
+		},
+		"invalid-timeout": {
+			profiles: testProgramProfiles(t, map[string]map[string]attr.Value{
+				"inventory": {
+					"program": types.ListValueMust(types.StringType, []attr.Value{
+						types.StringValue("/opt/bin/inventory"),
+					}),
+					"timeout": types.StringValue("30"),
+				},
+			}),
+			expectedError: true,
+		},
+		"negative-timeout": {
+			profiles: testProgramProfiles(t, map[string]map[string]attr.Value{
+				"inventory": {
+					"program": types.ListValueMust(types.StringType, []attr.Value{
+						types.StringValue("/opt/bin/inventory"),
+					}),
+					"timeout": types.StringValue("-1s"),
+				},
+			}),
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, diags := expandProgramProfiles(context.Background(), testCase.profiles)
+
+			if diags.HasError() != testCase.expectedError {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if testCase.expectedError {
+				return
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/terraform-providers/terraform-provider-external/externalprogram/protocol"
+)
+
+// programInvocation describes a single execution of an external program by
+// the external data source.
+type programInvocation struct {
+	Program []string
+
+	// Endpoint is the local endpoint the query is sent to instead of
+	// executing a program.
+	Endpoint string
+
+	WorkingDir  string
+	Environment map[string]string
+	Query       map[string]string
+
+	// EnvironmentScrubber removes variables from the environment inherited
+	// by the program, if not nil.
+	EnvironmentScrubber *environmentScrubber
+
+	// Timeout is the maximum duration of the execution, if positive.
+	Timeout time.Duration
+
+	// TimeoutPath is the attribute associated with timeout diagnostics.
+	TimeoutPath path.Path
+
+	// AttributePath is the attribute associated with diagnostics about the
+	// program.
+	AttributePath path.Path
+
+	// PositionalValues is the number of trailing program arguments which are
+	// query values, which are never recorded in the audit log.
+	PositionalValues int
+
+	// QueryDelivery is how the query is delivered to the program, which is
+	// one of the queryDelivery constants. The standard input is used if
+	// empty.
+	QueryDelivery string
+
+	// ProgramSHA256 is the expected SHA-256 digest of the program, if not
+	// empty.
+	ProgramSHA256 string
+
+	// ProgramDigests are the expected SHA-256 digests of programs by
+	// absolute path.
+	ProgramDigests map[string]string
+
+	// ProgramSignature is the path of the detached signature of the program,
+	// if not empty.
+	ProgramSignature string
+
+	// SignatureRequired is whether the program must have a valid signature,
+	// which is looked up next to the program if ProgramSignature is empty.
+	SignatureRequired bool
+
+	// TrustedKeys are the keys trusted to sign programs.
+	TrustedKeys *trustedKeys
+
+	// Redactor masks the sensitive values of the data source in the audit
+	// log, if not nil.
+	Redactor *redactor
+
+	// Credential is the user and group the program is executed as, if not
+	// nil.
+	Credential *programCredential
+
+	// Sandbox is the sandbox of the program, if any.
+	Sandbox *sandboxConfig
+
+	// Stderr additionally receives the standard error of the program, if
+	// not nil.
+	Stderr io.Writer
+}
+
+// runProgram executes the program of the invocation, writing the query to
+// its standard input, and returns its standard output along with the
+// resolved path of the program.
+func (n *externalDataSource) runProgram(ctx context.Context, invocation programInvocation) ([]byte, string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	queryJson, err := protocol.EncodeQuery(invocation.Query)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("query"),
+			"Query Handling Failed",
+			"The data source received an unexpected error while attempting to parse the query. "+
+				"This is always a bug in the external provider code and should be reported to the provider developers."+
+				fmt.Sprintf("\n\nError: %s", err),
+		)
+		return nil, "", diags
+	}
+
+	delivered, err := invocation.deliverQuery()
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("query"),
+			"Query Delivery Failed",
+			fmt.Sprintf("The data source was unable to deliver the query to the program using %q.", invocation.QueryDelivery)+
+				fmt.Sprintf("\n\nProgram: %s", invocation.Program[0])+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, "", diags
+	}
+
+	diags.Append(n.lookupProgram(delivered)...)
+	if diags.HasError() {
+		return nil, "", diags
+	}
+
+	if invocation.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, invocation.Timeout)
+		defer cancel()
+	}
+
+	cmd := delivered.command(ctx)
+	programPath := cmd.Path
+
+	verified, err := invocation.verifyProgram(cmd)
+	if err != nil {
+		if digestDiag, ok := invocation.programVerificationDiagnostic(programPath, err); ok {
+			diags.Append(digestDiag)
+			return nil, programPath, diags
+		}
+
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Program Verification Failed",
+			"The data source received an unexpected error while attempting to verify the program."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return nil, programPath, diags
+	}
+
+	// The copy of the verified program is removed once it exited.
+	defer verified.Close()
+
+	applySandbox(cmd, verified.sandbox(invocation.Sandbox))
+
+	var input []byte
+
+	if invocation.QueryDelivery == "" || invocation.QueryDelivery == queryDeliveryStdin {
+		input = queryJson
+	}
+
+	if invocation.Stderr != nil {
+		cmd.Stderr = invocation.Stderr
+	}
+
+	tflog.Trace(ctx, "Executing external program", map[string]interface{}{"program": cmd.String()})
+
+	start := time.Now()
+
+	resultJson, stderrStr, err := protocol.Execute(ctx, cmd, input)
+
+	tflog.Trace(ctx, "Executed external program", map[string]interface{}{"program": cmd.String(), "output": string(resultJson), "stderr": stderrStr})
+
+	if n.providerData.AuditLog != nil {
+		exitStatus := -1
+
+		if cmd.ProcessState != nil {
+			exitStatus = cmd.ProcessState.ExitCode()
+		}
+
+		auditArgs := append([]string(nil), cmd.Args[1:]...)
+
+		// Arguments are recorded before substitution, so query values are
+		// never recorded.
+		if invocation.QueryDelivery == queryDeliveryArguments {
+			auditArgs = append([]string(nil), invocation.Program[1:]...)
+		}
+
+		for i := len(auditArgs) - invocation.PositionalValues; i < len(auditArgs); i++ {
+			auditArgs[i] = auditRedactedValue
+		}
+
+		record := newAuditRecord(start, programPath, auditArgs, cmd.Dir, invocation.Query, exitStatus, resultJson, len(stderrStr))
+
+		diags.Append(n.writeAuditRecord(invocation, record, fmt.Sprintf("Program: %s", programPath))...)
+		if diags.HasError() {
+			return nil, programPath, diags
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, protocol.ErrTimeout) {
+			diags.Append(invocation.timeoutDiagnostic(fmt.Sprintf("Program: %s", programPath), err))
+			return nil, programPath, diags
+		}
+
+		if invocation.Sandbox != nil && invocation.Sandbox.Seccomp != nil && seccompViolation(cmd.ProcessState) {
+			diags.AddAttributeError(
+				path.Root("seccomp_profile"),
+				"Seccomp Profile Violation",
+				"The program was terminated by the kernel after attempting a system call denied by the seccomp profile."+
+					fmt.Sprintf("\n\nProgram: %s", programPath)+
+					fmt.Sprintf("\nSeccomp Profile: %s", invocation.Sandbox.Seccomp.Name)+
+					fmt.Sprintf("\nState: %s", err),
+			)
+			return nil, programPath, diags
+		}
+
+		if invocation.Sandbox != nil && invocation.Sandbox.Limits != nil {
+			if limit := limitExceeded(invocation.Sandbox.Limits, cmd.ProcessState); limit != nil {
+				detail := fmt.Sprintf("The program was terminated after exceeding the %s.", limit.Description)
+
+				if limit.Likely {
+					detail = fmt.Sprintf("The program was terminated by a signal, which likely indicates it exceeded the %s.", limit.Description)
+				}
+
+				diags.AddAttributeError(
+					path.Root("limits").AtName(limit.Attribute),
+					"External Program Resource Limit Exceeded",
+					detail+
+						fmt.Sprintf("\n\nProgram: %s", programPath)+
+						fmt.Sprintf("\nState: %s", err),
+				)
+				return nil, programPath, diags
+			}
+		}
+
+		var exitErr *protocol.ExitError
+
+		if errors.As(err, &exitErr) && exitErr.HasMessage() {
+			diags.AddAttributeError(
+				invocation.AttributePath,
+				"External Program Execution Failed",
+				"The data source received an unexpected error while attempting to execute the program."+
+					fmt.Sprintf("\n\nProgram: %s", programPath)+
+					fmt.Sprintf("\nError Message: %s", stderrStr)+
+					fmt.Sprintf("\nState: %s", err),
+			)
+			return nil, programPath, diags
+		}
+
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Program Execution Failed",
+			"The data source received an unexpected error while attempting to execute the program.\n\n"+
+				"The program was executed, however it returned no additional error messaging."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nState: %s", err),
+		)
+		return nil, programPath, diags
+	}
+
+	return resultJson, programPath, diags
+}
+
+// timeoutDiagnostic returns the diagnostic for an execution which exceeded
+// the timeout of the invocation.
+func (i programInvocation) timeoutDiagnostic(location string, err error) diag.Diagnostic {
+	return diag.NewAttributeErrorDiagnostic(
+		i.TimeoutPath,
+		"External Program Timed Out",
+		"The data source did not receive results within the configured timeout and the execution was terminated."+
+			fmt.Sprintf("\n\n%s", location)+
+			fmt.Sprintf("\nTimeout: %s", i.Timeout)+
+			fmt.Sprintf("\nState: %s", err),
+	)
+}
+
+// lookupProgram verifies the program of the invocation can be found.
+func (n *externalDataSource) lookupProgram(invocation programInvocation) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// first element is assumed to be an executable command, possibly found
+	// using the PATH environment variable.
+	_, err := exec.LookPath(invocation.Program[0])
+
+	// This is a workaround to preserve pre-existing behaviour prior to the upgrade to Go 1.19.
+	// Reference: https://github.com/hashicorp/terraform-provider-external/pull/192
+	//
+	// Practitioners are warned about this behaviour during validation and can opt out of it
+	// with the strict_program_lookup provider setting.
+	// Reference: https://github.com/hashicorp/terraform-provider-external/issues/197
+	if errors.Is(err, exec.ErrDot) {
+		if n.providerData.StrictProgramLookup {
+			diags.AddAttributeError(
+				invocation.AttributePath,
+				"External Program Lookup Failed",
+				"The data source could not execute the program because it was only found relative to the current directory "+
+					"and the provider is configured with 'strict_program_lookup'.\n\n"+
+					"If the expected program is relative to the Terraform configuration, it is recommended that the program name "+
+					"includes the interpolated value of 'path.module' before the program name to ensure that it is compatible "+
+					"with varying module usage. For example: \"${path.module}/my-program\""+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS)+
+					fmt.Sprintf("\nProgram: %s", invocation.Program[0])+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			return diags
+		}
+
+		err = nil
+	}
+
+	if err != nil {
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Program Lookup Failed",
+			"The data source received an unexpected error while attempting to parse the query. "+
+				`The data source received an unexpected error while attempting to find the program.
+
+The program must be accessible according to the platform where Terraform is running.
+
+If the expected program should be automatically found on the platform where Terraform is running, ensure that the program is in an expected directory. On Unix-based platforms, these directories are typically searched based on the '$PATH' environment variable. On Windows-based platforms, these directories are typically searched based on the '%PATH%' environment variable.
+
+If the expected program is relative to the Terraform configuration, it is recommended that the program name includes the interpolated value of 'path.module' before the program name to ensure that it is compatible with varying module usage. For example: "${path.module}/my-program"
+
+The program must also be executable according to the platform where Terraform is running. On Unix-based platforms, the file on the filesystem must have the executable bit set. On Windows-based platforms, no action is typically necessary.
+`+
+				fmt.Sprintf("\nPlatform: %s", runtime.GOOS)+
+				fmt.Sprintf("\nProgram: %s", invocation.Program[0])+
+				fmt.Sprintf("\nError: %s", err),
+		)
+		return diags
+	}
+
+	return diags
+}
+
+// command returns the command executing the program of the invocation. The
+// program must have been verified with lookupProgram.
+func (i programInvocation) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, i.Program[0], i.Program[1:]...)
+
+	// This is a workaround to preserve pre-existing behaviour prior to the upgrade to Go 1.19.
+	// Reference: https://github.com/hashicorp/terraform-provider-external/pull/192
+	//
+	// Strict program lookup has already returned an error in lookupProgram.
+	// Reference: https://github.com/hashicorp/terraform-provider-external/issues/197
+	if errors.Is(cmd.Err, exec.ErrDot) {
+		cmd.Err = nil
+	}
+
+	cmd.Dir = i.WorkingDir
+
+	if len(i.Environment) > 0 || i.EnvironmentScrubber != nil {
+		cmd.Env = os.Environ()
+
+		// Variables configured for the program are never scrubbed.
+		if i.EnvironmentScrubber != nil {
+			var scrubbed []string
+
+			cmd.Env, scrubbed = i.EnvironmentScrubber.scrub(cmd.Env)
+
+			if len(scrubbed) > 0 {
+				tflog.Debug(ctx, "Scrubbed environment of external program", map[string]interface{}{"program": i.Program[0], "variables": scrubbed})
+			}
+		}
+
+		for key, value := range i.Environment {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	// Sandboxed programs are switched to the credential by the shim, which
+	// requires the privileges of the provider to set up the sandbox.
+	if i.Credential != nil && i.Sandbox == nil {
+		applyCredential(cmd, i.Credential)
+	}
+
+	return cmd
+}
@@ -0,0 +1,244 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// sha256DigestRegexp matches SHA-256 digests encoded as hexadecimal.
+var sha256DigestRegexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// programDigestError is returned when the digest of a program does not match
+// its expected digest.
+type programDigestError struct {
+	Program  string
+	Expected string
+	Actual   string
+
+	// Provider is set if the expected digest is from the program_digests of
+	// the provider rather than the program_sha256 of the data source.
+	Provider bool
+}
+
+func (e *programDigestError) Error() string {
+	return fmt.Sprintf("SHA-256 digest of %s is %s, expected %s", e.Program, e.Actual, e.Expected)
+}
+
+// programFilePath returns the path of the program of the command, which is
+// relative to the working directory of the command if not absolute.
+func programFilePath(cmd *exec.Cmd) (string, error) {
+	program := cmd.Path
+
+	if !filepath.IsAbs(program) && cmd.Dir != "" {
+		program = filepath.Join(cmd.Dir, program)
+	}
+
+	return filepath.Abs(program)
+}
+
+// providerDigest returns the digest of the program in the program_digests
+// of the provider, which are keyed by absolute path, if any.
+func (i programInvocation) providerDigest(program string) string {
+	if digest, ok := i.ProgramDigests[program]; ok {
+		return digest
+	}
+
+	if resolved, err := filepath.EvalSymlinks(program); err == nil {
+		return i.ProgramDigests[resolved]
+	}
+
+	return ""
+}
+
+// verifiedProgram is the private copy of a verified program, which is
+// executed instead of the program, so the program cannot be replaced or
+// modified in place once verified.
+type verifiedProgram struct {
+	// dir is the private directory containing the copy.
+	dir string
+}
+
+// sandbox returns the sandbox of the program, with the copy remaining
+// readable within it even though it is within /tmp.
+func (v *verifiedProgram) sandbox(sandbox *sandboxConfig) *sandboxConfig {
+	if v == nil || sandbox == nil {
+		return sandbox
+	}
+
+	exposed := *sandbox
+	exposed.ReadOnlyPaths = append(append([]string(nil), sandbox.ReadOnlyPaths...), v.dir)
+
+	return &exposed
+}
+
+// Close removes the copy. It must only be called once the program exited, as
+// interpreters of scripts read the copy after the program was started.
+func (v *verifiedProgram) Close() error {
+	if v == nil {
+		return nil
+	}
+
+	return os.RemoveAll(v.dir)
+}
+
+// verifyProgram verifies the program of the command against the expected
+// digests and the signature of the invocation, if any. The program is copied
+// to a private directory while computing its digest, and the command is
+// modified to execute the copy, so the verified content is executed even if
+// the program is modified afterwards. The returned copy, if not nil, must be
+// closed once the program exited.
+func (i programInvocation) verifyProgram(cmd *exec.Cmd) (*verifiedProgram, error) {
+	verifySignature := i.ProgramSignature != "" || i.SignatureRequired
+
+	if cmd.Err != nil || (i.ProgramSHA256 == "" && len(i.ProgramDigests) == 0 && !verifySignature) {
+		return nil, nil
+	}
+
+	program, err := programFilePath(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := []*programDigestError{
+		{Expected: i.ProgramSHA256},
+		{Expected: i.providerDigest(program), Provider: true},
+	}
+
+	if expected[0].Expected == "" && expected[1].Expected == "" && !verifySignature {
+		return nil, nil
+	}
+
+	// Signatures are verified against the whole content of the program.
+	verified, copyPath, actual, content, err := copyProgram(program, verifySignature)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, digestErr := range expected {
+		if digestErr.Expected != "" && !strings.EqualFold(digestErr.Expected, actual) {
+			_ = verified.Close()
+
+			digestErr.Program = program
+			digestErr.Actual = actual
+
+			return nil, digestErr
+		}
+	}
+
+	if verifySignature {
+		if err := i.verifySignature(program, content); err != nil {
+			_ = verified.Close()
+			return nil, err
+		}
+	}
+
+	if i.Credential != nil {
+		if err := shareProgramCopy(copyPath, i.Credential); err != nil {
+			_ = verified.Close()
+			return nil, fmt.Errorf("unable to make the verified copy of the program readable by its user: %w", err)
+		}
+	}
+
+	cmd.Path = copyPath
+
+	return verified, nil
+}
+
+// copyProgram copies the program to a new private directory and returns the
+// copy along with the SHA-256 digest of the copied content, and the content
+// itself if requested, which is what must be verified. The copy keeps the
+// name of the program, so platforms selecting the executable format by
+// extension execute it the same way.
+func copyProgram(program string, keepContent bool) (*verifiedProgram, string, string, []byte, error) {
+	file, err := os.Open(program)
+	if err != nil {
+		return nil, "", "", nil, fmt.Errorf("unable to open program to verify it: %w", err)
+	}
+
+	defer file.Close()
+
+	dir, cleanup, err := privateTempDir()
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+
+	copyPath := filepath.Join(dir, filepath.Base(program))
+
+	copyFile, err := os.OpenFile(copyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o700)
+	if err != nil {
+		cleanup()
+		return nil, "", "", nil, err
+	}
+
+	hash := sha256.New()
+	writer := io.MultiWriter(copyFile, hash)
+
+	var content bytes.Buffer
+
+	if keepContent {
+		writer = io.MultiWriter(writer, &content)
+	}
+
+	_, err = io.Copy(writer, file)
+
+	if closeErr := copyFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		cleanup()
+		return nil, "", "", nil, fmt.Errorf("unable to copy program to verify it: %w", err)
+	}
+
+	// The copy is not modified once written.
+	if err := os.Chmod(copyPath, 0o500); err != nil {
+		cleanup()
+		return nil, "", "", nil, err
+	}
+
+	return &verifiedProgram{dir: dir}, copyPath, hex.EncodeToString(hash.Sum(nil)), content.Bytes(), nil
+}
+
+// programVerificationDiagnostic returns the diagnostic for an error
+// verifying the digests or the signature of the program of the invocation,
+// and whether the error is such an error.
+func (i programInvocation) programVerificationDiagnostic(programPath string, err error) (diag.Diagnostic, bool) {
+	var digestErr *programDigestError
+
+	if !errors.As(err, &digestErr) {
+		return i.programSignatureDiagnostic(programPath, err)
+	}
+
+	attributePath := path.Root("program_sha256")
+	source := "program_sha256"
+
+	if digestErr.Provider {
+		attributePath = i.AttributePath
+		source = "program_digests of the provider"
+	}
+
+	return diag.NewAttributeErrorDiagnostic(
+		attributePath,
+		"External Program Checksum Mismatch",
+		"The SHA-256 digest of the program does not match the expected digest, so the program was not executed. "+
+			"Verify the program was not modified, or update the expected digest if the program was intentionally replaced."+
+			fmt.Sprintf("\n\nProgram: %s", programPath)+
+			fmt.Sprintf("\nExpected Digest: %s (%s)", digestErr.Expected, source)+
+			fmt.Sprintf("\nActual Digest: %s", digestErr.Actual),
+	), true
+}
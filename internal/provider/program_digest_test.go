@@ -0,0 +1,228 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// writeDigestTestProgram writes an executable script printing the given
+// output and returns its path and SHA-256 digest.
+func writeDigestTestProgram(t *testing.T, dir string, output string) (string, string) {
+	t.Helper()
+
+	content := "#!/bin/sh\necho '" + output + "'\n"
+	program := filepath.Join(dir, "program")
+
+	if err := os.WriteFile(program, []byte(content), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte(content))
+
+	return program, hex.EncodeToString(digest[:])
+}
+
+func TestRunProgram_ProgramDigest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	program, digest := writeDigestTestProgram(t, dir, `{"verified":"true"}`)
+
+	link := filepath.Join(dir, "link")
+
+	if err := os.Symlink(program, link); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatch := "0000000000000000000000000000000000000000000000000000000000000000"
+
+	testCases := map[string]struct {
+		program        string
+		programSHA256  string
+		programDigests map[string]string
+		expectedError  string
+		expectedPath   path.Path
+	}{
+		"program_sha256": {
+			program:       program,
+			programSHA256: digest,
+		},
+		"program_sha256-uppercase": {
+			program:       program,
+			programSHA256: strings.ToUpper(digest),
+		},
+		"program_sha256-mismatch": {
+			program:       program,
+			programSHA256: mismatch,
+			expectedError: "External Program Checksum Mismatch",
+			expectedPath:  path.Root("program_sha256"),
+		},
+		"program_digests": {
+			program:        program,
+			programDigests: map[string]string{program: digest},
+		},
+		"program_digests-mismatch": {
+			program:        program,
+			programDigests: map[string]string{program: mismatch},
+			expectedError:  "External Program Checksum Mismatch",
+			expectedPath:   path.Root("program"),
+		},
+		"program_digests-symlink-mismatch": {
+			program:        link,
+			programDigests: map[string]string{program: mismatch},
+			expectedError:  "External Program Checksum Mismatch",
+			expectedPath:   path.Root("program"),
+		},
+		"program_digests-other-program": {
+			program:        program,
+			programDigests: map[string]string{filepath.Join(dir, "other"): mismatch},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:        []string{testCase.program},
+				AttributePath:  path.Root("program"),
+				ProgramSHA256:  testCase.programSHA256,
+				ProgramDigests: testCase.programDigests,
+			}
+
+			output, _, diags := n.runProgram(context.Background(), invocation)
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() {
+					t.Fatal("expected error, got none")
+				}
+
+				got := diags.Errors()[0]
+				withPath, ok := got.(interface{ Path() path.Path })
+
+				if got.Summary() != testCase.expectedError || !ok || !withPath.Path().Equal(testCase.expectedPath) {
+					t.Fatalf("expected %q error at %s, got: %v", testCase.expectedError, testCase.expectedPath, diags)
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if got, expected := string(output), "{\"verified\":\"true\"}\n"; got != expected {
+				t.Errorf("expected %q, got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestVerifyProgram_Modified(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	testCases := map[string]func(t *testing.T, dir string, program string){
+		// The program is replaced after it was verified.
+		"replaced": func(t *testing.T, dir string, program string) {
+			if err := os.Rename(program, program+".verified"); err != nil {
+				t.Fatal(err)
+			}
+
+			writeDigestTestProgram(t, dir, `{"version":"modified"}`)
+		},
+		// The program is modified in place through a descriptor opened
+		// before it was verified.
+		"modified-in-place": func(t *testing.T, dir string, program string) {
+			file, err := os.OpenFile(program, os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer file.Close()
+
+			if _, err := file.WriteAt([]byte("#!/bin/sh\necho '{\"version\":\"modified\"}'\n"), 0); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	for name, modify := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			program, digest := writeDigestTestProgram(t, dir, `{"version":"verified"}`)
+
+			invocation := programInvocation{Program: []string{program}, ProgramSHA256: digest}
+			cmd := invocation.command(context.Background())
+
+			verified, err := invocation.verifyProgram(cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer verified.Close()
+
+			modify(t, dir, program)
+
+			// No descriptor of the program is inherited by the program.
+			if len(cmd.ExtraFiles) > 0 {
+				t.Errorf("expected no extra files, got %d", len(cmd.ExtraFiles))
+			}
+
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, expected := string(output), "{\"version\":\"verified\"}\n"; got != expected {
+				t.Errorf("expected %q, got %q", expected, got)
+			}
+
+			if err := verified.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(cmd.Path); !os.IsNotExist(err) {
+				t.Errorf("expected the copy of the program to be removed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDataSource_ProgramSHA256_Mismatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	program, _ := writeDigestTestProgram(t, t.TempDir(), `{}`)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program        = ["` + program + `"]
+						program_sha256 = "0000000000000000000000000000000000000000000000000000000000000000"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`External Program Checksum Mismatch`),
+			},
+		},
+	})
+}
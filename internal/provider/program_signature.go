@@ -0,0 +1,203 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// signatureExtensions are the extensions of the detached signatures of
+// programs looked up next to programs when signatures are required.
+var signatureExtensions = []string{".sig", ".asc"}
+
+// trustedKeys are the public keys trusted to sign programs.
+type trustedKeys struct {
+	openPGP openpgp.EntityList
+	ed25519 []ed25519.PublicKey
+}
+
+// add parses a trusted key, which is either an ASCII armored OpenPGP public
+// key block, a PEM encoded ed25519 public key, or a base64 encoded raw
+// ed25519 public key.
+func (k *trustedKeys) add(key string) error {
+	key = strings.TrimSpace(key)
+
+	if strings.HasPrefix(key, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("invalid OpenPGP public key: %w", err)
+		}
+
+		k.openPGP = append(k.openPGP, entities...)
+
+		return nil
+	}
+
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("invalid PEM public key: %w", err)
+		}
+
+		ed25519Key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("unsupported public key type %T, expected ed25519", publicKey)
+		}
+
+		k.ed25519 = append(k.ed25519, ed25519Key)
+
+		return nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return errors.New("expected an ASCII armored OpenPGP public key block, a PEM encoded ed25519 public key, " +
+			"or a base64 encoded raw ed25519 public key")
+	}
+
+	k.ed25519 = append(k.ed25519, ed25519.PublicKey(raw))
+
+	return nil
+}
+
+// verify verifies the detached signature of the content against the trusted
+// keys. OpenPGP signatures can be binary or ASCII armored, while ed25519
+// signatures are raw or base64 encoded.
+func (k *trustedKeys) verify(content []byte, signature []byte) error {
+	if k == nil || (len(k.openPGP) == 0 && len(k.ed25519) == 0) {
+		return errors.New("no trusted keys are configured in the provider")
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err := openpgp.CheckArmoredDetachedSignature(k.openPGP, bytes.NewReader(content), bytes.NewReader(signature), nil)
+		return err
+	}
+
+	ed25519Signature := signature
+
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature))); err == nil {
+		ed25519Signature = decoded
+	}
+
+	if len(ed25519Signature) == ed25519.SignatureSize {
+		for _, key := range k.ed25519 {
+			if ed25519.Verify(key, content, ed25519Signature) {
+				return nil
+			}
+		}
+
+		if len(k.openPGP) == 0 {
+			return errors.New("ed25519 signature was not made by a trusted key")
+		}
+	}
+
+	// Binary OpenPGP signatures have no distinguishable prefix.
+	_, err := openpgp.CheckDetachedSignature(k.openPGP, bytes.NewReader(content), bytes.NewReader(signature), nil)
+
+	return err
+}
+
+// programSignatureError is returned when the signature of a program is
+// missing or invalid.
+type programSignatureError struct {
+	Program   string
+	Signature string
+	Err       error
+}
+
+func (e *programSignatureError) Error() string {
+	if e.Signature == "" {
+		return fmt.Sprintf("no signature found for %s", e.Program)
+	}
+
+	return fmt.Sprintf("signature %s of %s: %s", e.Signature, e.Program, e.Err)
+}
+
+// signaturePath returns the path of the detached signature of the program,
+// which is the configured signature or a file next to the program with one
+// of the signature extensions, or an empty string if there is none.
+func (i programInvocation) signaturePath(program string) string {
+	if i.ProgramSignature != "" {
+		return i.ProgramSignature
+	}
+
+	for _, extension := range signatureExtensions {
+		if _, err := os.Stat(program + extension); err == nil {
+			return program + extension
+		}
+	}
+
+	return ""
+}
+
+// verifySignature verifies the content of the program against its detached
+// signature.
+func (i programInvocation) verifySignature(program string, content []byte) error {
+	signaturePath := i.signaturePath(program)
+
+	if signaturePath == "" {
+		return &programSignatureError{Program: program}
+	}
+
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return &programSignatureError{Program: program, Signature: signaturePath, Err: err}
+	}
+
+	if err := i.TrustedKeys.verify(content, signature); err != nil {
+		return &programSignatureError{Program: program, Signature: signaturePath, Err: err}
+	}
+
+	return nil
+}
+
+// programSignatureDiagnostic returns the diagnostic for an error verifying
+// the signature of the program of the invocation, and whether the error is
+// such an error.
+func (i programInvocation) programSignatureDiagnostic(programPath string, err error) (diag.Diagnostic, bool) {
+	var signatureErr *programSignatureError
+
+	if !errors.As(err, &signatureErr) {
+		return nil, false
+	}
+
+	attributePath := i.AttributePath
+
+	if i.ProgramSignature != "" {
+		attributePath = path.Root("program_signature")
+	}
+
+	if signatureErr.Signature == "" {
+		return diag.NewAttributeErrorDiagnostic(
+			attributePath,
+			"External Program Signature Missing",
+			"The provider requires programs to be signed, however no detached signature was found for the program, "+
+				"so the program was not executed. Configure the path of the signature with 'program_signature', or place "+
+				"the signature next to the program with the extension .sig or .asc."+
+				fmt.Sprintf("\n\nProgram: %s", programPath),
+		), true
+	}
+
+	return diag.NewAttributeErrorDiagnostic(
+		attributePath,
+		"External Program Signature Invalid",
+		"The signature of the program could not be verified with the trusted keys of the provider, so the program "+
+			"was not executed. Verify the program was not modified and was signed by a trusted key."+
+			fmt.Sprintf("\n\nProgram: %s", programPath)+
+			fmt.Sprintf("\nSignature: %s", signatureErr.Signature)+
+			fmt.Sprintf("\nError: %s", signatureErr.Err),
+	), true
+}
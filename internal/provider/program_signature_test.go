@@ -0,0 +1,275 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// newTestOpenPGPEntity returns a new OpenPGP signing key and its ASCII
+// armored public key block.
+func newTestOpenPGPEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var publicKey bytes.Buffer
+
+	writer, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := entity.Serialize(writer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return entity, publicKey.String()
+}
+
+func TestTrustedKeys_Add(t *testing.T) {
+	t.Parallel()
+
+	_, openPGPKey := newTestOpenPGPEntity(t)
+
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		key           string
+		expectedError string
+	}{
+		"openpgp": {
+			key: openPGPKey,
+		},
+		"ed25519-pem": {
+			key: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})),
+		},
+		"ed25519-base64": {
+			key: base64.StdEncoding.EncodeToString(publicKey),
+		},
+		"openpgp-invalid": {
+			key:           "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\ninvalid\n-----END PGP PUBLIC KEY BLOCK-----",
+			expectedError: "invalid OpenPGP public key",
+		},
+		"base64-wrong-size": {
+			key:           base64.StdEncoding.EncodeToString([]byte("short")),
+			expectedError: "expected an ASCII armored OpenPGP public key block",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&trustedKeys{}).add(testCase.key)
+
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("expected error containing %q, got: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestRunProgram_ProgramSignature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	program, _ := writeDigestTestProgram(t, dir, `{"signed":"true"}`)
+
+	content, err := os.ReadFile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entity, openPGPKey := newTestOpenPGPEntity(t)
+	_, untrustedOpenPGPKey := newTestOpenPGPEntity(t)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	untrustedPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var armoredSignature, binarySignature bytes.Buffer
+
+	if err := openpgp.ArmoredDetachSign(&armoredSignature, entity, bytes.NewReader(content), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := openpgp.DetachSign(&binarySignature, entity, bytes.NewReader(content), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Signature := ed25519.Sign(privateKey, content)
+
+	signatures := map[string][]byte{
+		"openpgp.asc":        armoredSignature.Bytes(),
+		"openpgp.sig":        binarySignature.Bytes(),
+		"ed25519.sig":        ed25519Signature,
+		"ed25519.sig.base64": []byte(base64.StdEncoding.EncodeToString(ed25519Signature) + "\n"),
+	}
+
+	for name, signature := range signatures {
+		if err := os.WriteFile(filepath.Join(dir, name), signature, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The program with a signature next to it.
+	signedDir := t.TempDir()
+	signedProgram, _ := writeDigestTestProgram(t, signedDir, `{"signed":"true"}`)
+
+	if err := os.WriteFile(signedProgram+".sig", ed25519Signature, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	trusted := func(keys ...string) *trustedKeys {
+		k := &trustedKeys{}
+
+		for _, key := range keys {
+			if err := k.add(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return k
+	}
+
+	testCases := map[string]struct {
+		program           string
+		signature         string
+		required          bool
+		keys              *trustedKeys
+		expectedError     string
+		expectedAttribute string
+	}{
+		"openpgp-armored": {
+			program:   program,
+			signature: filepath.Join(dir, "openpgp.asc"),
+			keys:      trusted(openPGPKey),
+		},
+		"openpgp-binary": {
+			program:   program,
+			signature: filepath.Join(dir, "openpgp.sig"),
+			keys:      trusted(base64.StdEncoding.EncodeToString(publicKey), openPGPKey),
+		},
+		"openpgp-untrusted": {
+			program:           program,
+			signature:         filepath.Join(dir, "openpgp.asc"),
+			keys:              trusted(untrustedOpenPGPKey),
+			expectedError:     "External Program Signature Invalid",
+			expectedAttribute: "program_signature",
+		},
+		"ed25519": {
+			program:   program,
+			signature: filepath.Join(dir, "ed25519.sig"),
+			keys:      trusted(base64.StdEncoding.EncodeToString(publicKey)),
+		},
+		"ed25519-base64": {
+			program:   program,
+			signature: filepath.Join(dir, "ed25519.sig.base64"),
+			keys:      trusted(openPGPKey, base64.StdEncoding.EncodeToString(publicKey)),
+		},
+		"ed25519-untrusted": {
+			program:           program,
+			signature:         filepath.Join(dir, "ed25519.sig"),
+			keys:              trusted(base64.StdEncoding.EncodeToString(untrustedPublicKey)),
+			expectedError:     "External Program Signature Invalid",
+			expectedAttribute: "program_signature",
+		},
+		"required-next-to-program": {
+			program:  signedProgram,
+			required: true,
+			keys:     trusted(base64.StdEncoding.EncodeToString(publicKey)),
+		},
+		"required-missing": {
+			program:           program,
+			required:          true,
+			keys:              trusted(base64.StdEncoding.EncodeToString(publicKey)),
+			expectedError:     "External Program Signature Missing",
+			expectedAttribute: "program",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:           []string{testCase.program},
+				AttributePath:     path.Root("program"),
+				ProgramSignature:  testCase.signature,
+				SignatureRequired: testCase.required,
+				TrustedKeys:       testCase.keys,
+			}
+
+			output, _, diags := n.runProgram(context.Background(), invocation)
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() {
+					t.Fatal("expected error, got none")
+				}
+
+				got := diags.Errors()[0]
+				withPath, ok := got.(interface{ Path() path.Path })
+
+				if got.Summary() != testCase.expectedError || !ok || !withPath.Path().Equal(path.Root(testCase.expectedAttribute)) {
+					t.Fatalf("expected %q error at %s, got: %v", testCase.expectedError, testCase.expectedAttribute, diags)
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if got, expected := string(output), "{\"signed\":\"true\"}\n"; got != expected {
+				t.Errorf("expected %q, got %q", expected, got)
+			}
+		})
+	}
+}
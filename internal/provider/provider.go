@@ -5,10 +5,22 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 var _ provider.Provider = (*externalProvider)(nil)
@@ -24,12 +36,208 @@ func (p *externalProvider) Metadata(ctx context.Context, req provider.MetadataRe
 }
 
 func (p *externalProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config externalProviderModel
 
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	profiles, diags := expandProgramProfiles(ctx, config.Profiles)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	providerData := &externalProviderData{
+		StrictProgramLookup: config.StrictProgramLookup.ValueBool(),
+		Profiles:            profiles,
+		Shell:               defaultShell(),
+		EnvironmentScrubber: newEnvironmentScrubber(nil, nil),
+		Workers:             newWorkerPool(),
+		Plugins:             newPluginPool(),
+	}
+
+	if !config.Shell.IsNull() && !config.Shell.IsUnknown() {
+		var shell []types.String
+
+		diags = config.Shell.ElementsAs(ctx, &shell, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		providerData.Shell = filterProgram(shell)
+
+		if len(providerData.Shell) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("shell"),
+				"Invalid Shell",
+				"The provider was configured with a shell without a program to execute. Verify the configuration contains at least one non-empty value.",
+			)
+			return
+		}
+	}
+
+	if !config.ProgramDigests.IsNull() && !config.ProgramDigests.IsUnknown() {
+		diags = config.ProgramDigests.ElementsAs(ctx, &providerData.ProgramDigests, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		for program := range providerData.ProgramDigests {
+			if !filepath.IsAbs(program) {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("program_digests").AtMapKey(program),
+					"Invalid Program Digests",
+					"The provider was configured with a program digest for a relative path. Programs must be identified "+
+						"by their absolute path, such as the path found in the '$PATH' environment variable."+
+						fmt.Sprintf("\n\nProgram: %s", program),
+				)
+				return
+			}
+		}
+	}
+
+	if !config.RedactionPatterns.IsNull() && !config.RedactionPatterns.IsUnknown() {
+		var patterns []string
+
+		diags = config.RedactionPatterns.ElementsAs(ctx, &patterns, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		for i, pattern := range patterns {
+			expression, err := regexp.Compile(pattern)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("redaction_patterns").AtListIndex(i),
+					"Invalid Redaction Pattern",
+					"The provider was configured with an invalid regular expression."+
+						fmt.Sprintf("\n\nPattern: %s", pattern)+
+						fmt.Sprintf("\nError: %s", err),
+				)
+				return
+			}
+
+			providerData.RedactionPatterns = append(providerData.RedactionPatterns, expression)
+		}
+	}
+
+	providerData.ExecutionPolicy, diags = expandExecutionPolicy(ctx, config.ExecutionPolicy)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The credential is resolved again by data sources, however a provider
+	// without the privileges to use it is rejected early.
+	_, diags = runAsCredential(config.RunAsUser.ValueString(), config.RunAsGroup.ValueString(), path.Root("run_as_user"), path.Root("run_as_group"))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	providerData.RunAsUser = config.RunAsUser.ValueString()
+	providerData.RunAsGroup = config.RunAsGroup.ValueString()
+
+	if config.ScrubEnvironment != nil && !config.ScrubEnvironment.Enabled.IsNull() && !config.ScrubEnvironment.Enabled.ValueBool() {
+		providerData.EnvironmentScrubber = nil
+	} else if config.ScrubEnvironment != nil {
+		var patterns, allowed []string
+
+		diags = config.ScrubEnvironment.Patterns.ElementsAs(ctx, &patterns, false)
+		resp.Diagnostics.Append(diags...)
+
+		diags = config.ScrubEnvironment.Allowed.ElementsAs(ctx, &allowed, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		for i, pattern := range patterns {
+			if err := validateScrubPattern(pattern); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("scrub_environment").AtName("patterns").AtListIndex(i),
+					"Invalid Scrub Pattern",
+					"The provider was configured with an invalid pattern of environment variable names."+
+						fmt.Sprintf("\n\nPattern: %s", pattern)+
+						fmt.Sprintf("\nError: %s", err),
+				)
+				return
+			}
+		}
+
+		providerData.EnvironmentScrubber = newEnvironmentScrubber(patterns, allowed)
+	}
+
+	if config.ProgramSignatures != nil {
+		var keys []types.String
+
+		diags = config.ProgramSignatures.TrustedKeys.ElementsAs(ctx, &keys, false)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		providerData.TrustedKeys = &trustedKeys{}
+		providerData.SignatureRequired = config.ProgramSignatures.Required.ValueBool()
+
+		for i, key := range keys {
+			if err := providerData.TrustedKeys.add(key.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("program_signatures").AtName("trusted_keys").AtListIndex(i),
+					"Invalid Trusted Key",
+					"The provider was unable to parse a trusted key."+
+						fmt.Sprintf("\n\nError: %s", err),
+				)
+				return
+			}
+		}
+	}
+
+	if config.Sandbox != nil {
+		providerData.SandboxRequired = config.Sandbox.Required.ValueBool()
+		providerData.SandboxNetworkDenied = !config.Sandbox.AllowNetwork.IsNull() && !config.Sandbox.AllowNetwork.ValueBool()
+
+		if providerData.SandboxRequired && !sandboxSupported {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("sandbox").AtName("required"),
+				"Unsupported Sandbox",
+				"Programs can only be run in a sandbox on Linux, so the provider cannot require it on this platform."+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+			)
+			return
+		}
+	}
+
+	if config.AuditLogPath.ValueString() != "" {
+		auditLog, err := newAuditLog(config.AuditLogPath.ValueString())
+
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log_path"),
+				"Invalid Audit Log Path",
+				"The provider was unable to open the audit log for appending. Verify the directory exists and is writable."+
+					fmt.Sprintf("\n\nPath: %s", config.AuditLogPath.ValueString())+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			return
+		}
+
+		providerData.AuditLog = auditLog
+	}
+
+	resp.DataSourceData = providerData
 }
 
 func (p *externalProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewExternalDataSource,
+		NewStarlarkDataSource,
 	}
 }
 
@@ -37,5 +245,293 @@ func (p *externalProvider) Resources(ctx context.Context) []func() resource.Reso
 	return nil
 }
 
-func (p *externalProvider) Schema(context.Context, provider.SchemaRequest, *provider.SchemaResponse) {
+func (p *externalProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"audit_log_path": schema.StringAttribute{
+				Description: "Path of a file to which a JSON Lines record is appended for every executed program. " +
+					"Each record contains the time, resolved program path, arguments, working directory, query keys, " +
+					"exit status, duration, output sizes and a SHA-256 digest of the output of the program. Query " +
+					"values are never recorded. Arguments in the form of `name=value`, or following a flag such as " +
+					"`--token` or `-p`, whose name suggests a credential have their value redacted, as well as the " +
+					"values of `sensitive_query_keys` and the matches of `redaction_patterns`. The file is created " +
+					"with permissions `0600` if it does not exist.",
+				Optional: true,
+			},
+
+			"shell": schema.ListAttribute{
+				Description: "The shell used to execute the `command` attribute of the `external` data source. " +
+					"The command is appended as the next argument, followed by the name of the script (`$0`) and " +
+					"the values of the query as positional parameters, so the shell must follow the calling " +
+					"convention of `sh -c`. Defaults to `[\"/bin/sh\", \"-c\"]` on Unix-based platforms. There " +
+					"is no default on Windows, where a shell such as `[\"bash\", \"-c\"]` must be configured.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+
+			"program_digests": schema.MapAttribute{
+				Description: "A map of absolute program paths to the expected SHA-256 digests of the programs, " +
+					"encoded as hexadecimal. Before executing a program of the `external` data source found at one " +
+					"of these paths, its digest is verified and the program is not executed on mismatch.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.Map{
+					mapvalidator.ValueStringsAre(
+						stringvalidator.RegexMatches(sha256DigestRegexp, "must be a SHA-256 digest encoded as 64 hexadecimal characters"),
+					),
+				},
+			},
+
+			"execution_policy": schema.MapAttribute{
+				Description: "A map of rule names to CEL expressions which must all evaluate to `true` for a program " +
+					"of the `external` data source to be executed. Rules can use `program.path`, the absolute path " +
+					"of the program, `program.args`, its arguments as configured, `program.working_dir` and " +
+					"`query`, the list of query keys. For example: " +
+					"`program.path.startsWith('/opt/tf-helpers/') && !('password' in query)`",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"redaction_patterns": schema.ListAttribute{
+				Description: "Regular expressions whose matches are masked in the logs, diagnostics and audit log " +
+					"records of the `external` data source, such as the standard error of programs, in the syntax of the Go regexp " +
+					"package. For example: `[\"ghp_[A-Za-z0-9]+\"]`",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"run_as_user": schema.StringAttribute{
+				Description: "The name or numeric ID of the user programs of the `external` data source are " +
+					"executed as, unless the data source sets `run_as_user`. The group defaults to the primary " +
+					"group of the user. Executing programs as a different user requires the provider to run as " +
+					"root, and is only supported on Unix platforms.",
+				Optional: true,
+			},
+
+			"run_as_group": schema.StringAttribute{
+				Description: "The name or numeric ID of the group programs of the `external` data source are " +
+					"executed as, unless the data source sets `run_as_group`. Supplementary groups of the " +
+					"provider are dropped.",
+				Optional: true,
+			},
+
+			"strict_program_lookup": schema.BoolAttribute{
+				Description: "When `true`, programs are never resolved relative to the current directory. " +
+					"A program name without a path separator which is only found through the current " +
+					"directory, or through a relative entry in the `PATH` environment variable, causes " +
+					"an error instead of being executed. Defaults to `false`.",
+				Optional: true,
+			},
+
+			"profiles": schema.MapNestedAttribute{
+				Description: "A map of named program profiles which can be referenced by the `profile` " +
+					"attribute of the `external` data source instead of configuring a `program`.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"program": schema.ListAttribute{
+							Description: "A list of strings, whose first element is the program to run and whose " +
+								"subsequent elements are optional command line arguments to the program.",
+							ElementType: types.StringType,
+							Required:    true,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+							},
+						},
+
+						"query": schema.MapAttribute{
+							Description: "A map of default query values. Query values configured on the data " +
+								"source take precedence over these values.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+
+						"environment": schema.MapAttribute{
+							Description: "A map of environment variables to set for the program, in addition " +
+								"to the environment variables visible to the Terraform process.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+
+						"working_dir": schema.StringAttribute{
+							Description: "Working directory of the program. The `working_dir` attribute of the " +
+								"data source takes precedence over this value.",
+							Optional: true,
+						},
+
+						"timeout": schema.StringAttribute{
+							Description: "The maximum duration the program may run, such as `30s` or `5m`, " +
+								"after which it is terminated. If not supplied, the program is not time limited.",
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"program_signatures": schema.SingleNestedBlock{
+				Description: "Settings of the verification of detached signatures of programs executed by the " +
+					"`external` data source.",
+				Attributes: map[string]schema.Attribute{
+					"trusted_keys": schema.ListAttribute{
+						Description: "The public keys trusted to sign programs. Each key is either an ASCII armored " +
+							"OpenPGP public key block, a PEM encoded ed25519 public key, or a base64 encoded raw ed25519 " +
+							"public key.",
+						ElementType: types.StringType,
+						Optional:    true,
+						Validators: []validator.List{
+							listvalidator.SizeAtLeast(1),
+						},
+					},
+
+					"required": schema.BoolAttribute{
+						Description: "When `true`, every program must have a valid signature, found at the " +
+							"`program_signature` of the data source or next to the program with the extension `.sig` or " +
+							"`.asc`. Defaults to `false`.",
+						Optional: true,
+						Validators: []validator.Bool{
+							boolvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName("trusted_keys")),
+						},
+					},
+				},
+			},
+
+			"scrub_environment": schema.SingleNestedBlock{
+				Description: "Settings of the removal of variables likely to contain credentials from the " +
+					"environment inherited by programs of the `external` data source, such as `AWS_*`, `GOOGLE_*`, " +
+					"`ARM_*`, `TF_VAR_*`, `*_TOKEN` and `*_SECRET`, which is enabled by default. Variables " +
+					"configured in a profile or delivering the query are kept.",
+				Attributes: map[string]schema.Attribute{
+					"enabled": schema.BoolAttribute{
+						Description: "Whether to remove the variables from the environment of programs. When " +
+							"`false`, programs inherit the whole environment of Terraform, and `patterns` and " +
+							"`allowed` are ignored. Defaults to `true`.",
+						Optional: true,
+					},
+
+					"patterns": schema.ListAttribute{
+						Description: "Additional patterns of the names of removed variables, where `*` matches any " +
+							"sequence of characters. Names are matched case-insensitively.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+
+					"allowed": schema.ListAttribute{
+						Description: "The names of variables which are kept even though they match a pattern, such " +
+							"as `AWS_REGION`.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+				},
+			},
+
+			"sandbox": schema.SingleNestedBlock{
+				Description: "Settings of the sandbox of programs executed by the `external` data source, which " +
+					"is only supported on Linux.",
+				Attributes: map[string]schema.Attribute{
+					"required": schema.BoolAttribute{
+						Description: "When `true`, every program runs in a sandbox, using the default settings if " +
+							"the data source has no `sandbox` block, and plugins cannot be used. Defaults to `false`.",
+						Optional: true,
+					},
+
+					"allow_network": schema.BoolAttribute{
+						Description: "Whether the `sandbox` block of the data source can enable network access " +
+							"with its `network` attribute. Defaults to `true`.",
+						Optional: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+type externalProviderModel struct {
+	AuditLogPath        types.String `tfsdk:"audit_log_path"`
+	StrictProgramLookup types.Bool   `tfsdk:"strict_program_lookup"`
+	Profiles            types.Map    `tfsdk:"profiles"`
+	Shell               types.List   `tfsdk:"shell"`
+	ProgramDigests      types.Map    `tfsdk:"program_digests"`
+	RedactionPatterns   types.List   `tfsdk:"redaction_patterns"`
+	ExecutionPolicy     types.Map    `tfsdk:"execution_policy"`
+	RunAsUser           types.String `tfsdk:"run_as_user"`
+	RunAsGroup          types.String `tfsdk:"run_as_group"`
+
+	Sandbox           *providerSandboxModel           `tfsdk:"sandbox"`
+	ProgramSignatures *providerProgramSignaturesModel `tfsdk:"program_signatures"`
+	ScrubEnvironment  *providerScrubEnvironmentModel  `tfsdk:"scrub_environment"`
+}
+
+type providerProgramSignaturesModel struct {
+	TrustedKeys types.List `tfsdk:"trusted_keys"`
+	Required    types.Bool `tfsdk:"required"`
+}
+
+// externalProviderData is the provider configuration shared with the
+// data sources of this provider.
+type externalProviderData struct {
+	StrictProgramLookup bool
+	Profiles            map[string]externalProgramProfile
+	Shell               []string
+
+	// ProgramDigests are the expected SHA-256 digests of programs by
+	// absolute path.
+	ProgramDigests map[string]string
+
+	// RedactionPatterns are regular expressions whose matches are masked in
+	// logs and diagnostics.
+	RedactionPatterns []*regexp.Regexp
+
+	// ExecutionPolicy are the rules programs must satisfy to be executed.
+	ExecutionPolicy executionPolicy
+
+	// RunAsUser and RunAsGroup are the user and group programs are executed
+	// as, unless the data source configures its own, if not empty.
+	RunAsUser  string
+	RunAsGroup string
+
+	// EnvironmentScrubber removes credentials from the environment inherited
+	// by programs, or is nil if scrubbing is disabled.
+	EnvironmentScrubber *environmentScrubber
+
+	// TrustedKeys are the keys trusted to sign programs, or nil if none are
+	// configured.
+	TrustedKeys *trustedKeys
+
+	// SignatureRequired is whether every program must have a valid
+	// signature.
+	SignatureRequired bool
+
+	// SandboxRequired is whether every program must run in a sandbox.
+	SandboxRequired bool
+
+	// SandboxNetworkDenied is whether sandboxed programs cannot have network
+	// access.
+	SandboxNetworkDenied bool
+
+	// AuditLog is nil unless an audit log path is configured.
+	AuditLog *auditLog
+
+	// Workers contains the running programs of data sources with persistent
+	// set. It is nil if the provider has not been configured.
+	Workers *workerPool
+
+	// Plugins contains the running helpers of data sources with plugin set.
+	// It is nil if the provider has not been configured.
+	Plugins *pluginPool
+}
+
+// defaultShell returns the shell used to execute commands when none is
+// configured. Windows has no shell following the calling convention of
+// sh -c, so there is no default.
+func defaultShell() []string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	return []string{"/bin/sh", "-c"}
 }
@@ -0,0 +1,219 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// queryDeliveryStdin writes the query to the standard input of the
+	// program as a JSON object. This is the default.
+	queryDeliveryStdin = "stdin"
+
+	// queryDeliveryEnvironment exposes every query value as an environment
+	// variable prefixed with queryEnvironmentPrefix.
+	queryDeliveryEnvironment = "environment"
+
+	// queryDeliveryArguments substitutes {{ .key }} placeholders in the
+	// program arguments with query values.
+	queryDeliveryArguments = "arguments"
+
+	// queryEnvironmentPrefix prefixes the environment variables of query
+	// values delivered through the environment.
+	queryEnvironmentPrefix = "TF_QUERY_"
+)
+
+// queryEnvironmentKeyRegexp matches query keys which can be delivered as
+// environment variables.
+var queryEnvironmentKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// queryEnvironment returns the environment variables of the query, which are
+// named after the upper-cased query keys with the TF_QUERY_ prefix.
+func queryEnvironment(query map[string]string) (map[string]string, error) {
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	environment := make(map[string]string, len(query))
+	names := make(map[string]string, len(query))
+
+	for _, key := range keys {
+		if !queryEnvironmentKeyRegexp.MatchString(key) {
+			return nil, fmt.Errorf("query key %q cannot be delivered as an environment variable, keys may only contain letters, digits and underscores", key)
+		}
+
+		name := queryEnvironmentPrefix + strings.ToUpper(key)
+
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("query keys %q and %q are both delivered as the environment variable %s", other, key, name)
+		}
+
+		if strings.ContainsRune(query[key], 0) {
+			return nil, fmt.Errorf("query value of key %q contains a NUL character", key)
+		}
+
+		names[name] = key
+		environment[name] = query[key]
+	}
+
+	return environment, nil
+}
+
+// parseArgumentTemplate parses a program argument containing {{ .key }}
+// placeholders. Placeholders referencing keys missing from the query fail
+// the substitution rather than producing an empty value. Other actions of
+// templates, such as conditions, pipelines and functions, are rejected, so
+// arguments only ever contain the configured text and query values.
+func parseArgumentTemplate(argument string) (*template.Template, error) {
+	tmpl, err := template.New("argument").Option("missingkey=error").Parse(argument)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("argument %q defines templates, only placeholders in the form {{ .key }} are supported", argument)
+	}
+
+	if tmpl.Tree == nil {
+		return tmpl, nil
+	}
+
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+		case *parse.ActionNode:
+			if !isPlaceholder(node.Pipe) {
+				return nil, fmt.Errorf("argument %q contains the action %s, only placeholders in the form {{ .key }} are supported", argument, node)
+			}
+		default:
+			return nil, fmt.Errorf("argument %q contains the action %s, only placeholders in the form {{ .key }} are supported", argument, node)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// isPlaceholder returns whether the pipeline of an action only references a
+// single query key, as in {{ .key }}.
+func isPlaceholder(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+
+	return ok && len(field.Ident) == 1
+}
+
+// expandArguments substitutes the placeholders in the arguments of the
+// program with query values. The program itself is never substituted. No
+// shell is involved, so values are never interpreted, and each argument is
+// passed to the program as a single argument regardless of its content.
+//
+// Values could still turn an argument into an option of the program, such as
+// --config=/etc/shadow, so arguments starting with a value starting with -
+// are rejected, unless they follow a -- argument ending the options.
+func expandArguments(program []string, query map[string]string) ([]string, error) {
+	expanded := make([]string, len(program))
+	endOfOptions := false
+
+	// Values are never allowed to contain NUL characters, so values marked
+	// with a leading NUL character identify the origin of a leading -.
+	markedQuery := make(map[string]string, len(query))
+
+	for key, value := range query {
+		markedQuery[key] = "\x00" + value
+	}
+
+	for i, argument := range program {
+		if i == 0 {
+			expanded[i] = argument
+			continue
+		}
+
+		if argument == "--" {
+			expanded[i] = argument
+			endOfOptions = true
+			continue
+		}
+
+		tmpl, err := parseArgumentTemplate(argument)
+		if err != nil {
+			return nil, err
+		}
+
+		var builder strings.Builder
+
+		if err := tmpl.Execute(&builder, query); err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+
+		if strings.ContainsRune(builder.String(), 0) {
+			return nil, fmt.Errorf("argument %d contains a NUL character after substitution", i)
+		}
+
+		if !endOfOptions && strings.HasPrefix(builder.String(), "-") && startsWithValue(tmpl, markedQuery) {
+			return nil, fmt.Errorf("argument %d starts with \"-\" after substitution and could be interpreted as an option, placeholders of values starting with \"-\" must follow a \"--\" argument", i)
+		}
+
+		expanded[i] = builder.String()
+	}
+
+	return expanded, nil
+}
+
+// startsWithValue returns whether the argument template starts with a query
+// value when executed with the marked query.
+func startsWithValue(tmpl *template.Template, markedQuery map[string]string) bool {
+	var builder strings.Builder
+
+	if err := tmpl.Execute(&builder, markedQuery); err != nil {
+		return true
+	}
+
+	return strings.HasPrefix(builder.String(), "\x00")
+}
+
+// deliverQuery returns the invocation with its query delivered through the
+// environment or the program arguments, if configured.
+func (i programInvocation) deliverQuery() (programInvocation, error) {
+	switch i.QueryDelivery {
+	case queryDeliveryEnvironment:
+		queryEnv, err := queryEnvironment(i.Query)
+		if err != nil {
+			return i, err
+		}
+
+		environment := make(map[string]string, len(i.Environment)+len(queryEnv))
+
+		for key, value := range i.Environment {
+			environment[key] = value
+		}
+
+		for key, value := range queryEnv {
+			environment[key] = value
+		}
+
+		i.Environment = environment
+	case queryDeliveryArguments:
+		program, err := expandArguments(i.Program, i.Query)
+		if err != nil {
+			return i, err
+		}
+
+		i.Program = program
+	}
+
+	return i, nil
+}
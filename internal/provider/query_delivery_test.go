@@ -0,0 +1,333 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestDataSource_QueryDelivery_Environment(t *testing.T) {
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					data "external" "test" {
+						program        = [%[1]q]
+						query_delivery = "environment"
+
+						query = {
+							value = "pizza"
+						}
+					}
+				`, programPath),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.test", "result.query_value", "pizza"),
+					resource.TestCheckResourceAttr("data.external.test", "result.value", "pizza"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_QueryDelivery_Arguments(t *testing.T) {
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					data "external" "test" {
+						program        = [%[1]q, "--value={{ .value }}"]
+						query_delivery = "arguments"
+
+						query = {
+							value = "pizza; rm -rf /"
+						}
+					}
+				`, programPath),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external.test", "result.argument", "--value=pizza; rm -rf /"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_QueryDelivery_InvalidTemplate(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						program        = ["test", "{{ .value "]
+						query_delivery = "arguments"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Program Argument Template`),
+			},
+		},
+	})
+}
+
+func TestDataSource_QueryDelivery_Unsupported(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						command        = "test"
+						query_delivery = "environment"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Unsupported Query Delivery`),
+			},
+		},
+	})
+}
+
+func TestQueryEnvironment(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		query         map[string]string
+		expected      map[string]string
+		expectedError bool
+	}{
+		"valid": {
+			query:    map[string]string{"region": "eu-west-1", "instance_type": "t3.micro"},
+			expected: map[string]string{"TF_QUERY_REGION": "eu-west-1", "TF_QUERY_INSTANCE_TYPE": "t3.micro"},
+		},
+		"invalid-key": {
+			query:         map[string]string{"instance-type": "t3.micro"},
+			expectedError: true,
+		},
+		"colliding-keys": {
+			query:         map[string]string{"region": "a", "REGION": "b"},
+			expectedError: true,
+		},
+		"nul-value": {
+			query:         map[string]string{"region": "a\x00b"},
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := queryEnvironment(testCase.query)
+
+			if (err != nil) != testCase.expectedError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
+
+func TestExpandArguments(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		program       []string
+		query         map[string]string
+		expected      []string
+		expectedError bool
+	}{
+		"placeholders": {
+			program:  []string{"aws", "ec2", "describe-instances", "--region", "{{ .region }}", "--filters=Name=tag:Name,Values={{ .name }}"},
+			query:    map[string]string{"region": "eu-west-1", "name": "web server"},
+			expected: []string{"aws", "ec2", "describe-instances", "--region", "eu-west-1", "--filters=Name=tag:Name,Values=web server"},
+		},
+		"program-not-substituted": {
+			program:  []string{"{{ .program }}", "{{ .program }}"},
+			query:    map[string]string{"program": "rm"},
+			expected: []string{"{{ .program }}", "rm"},
+		},
+		"shell-metacharacters": {
+			program:  []string{"echo", "{{ .value }}"},
+			query:    map[string]string{"value": "$(id); `id` | id"},
+			expected: []string{"echo", "$(id); `id` | id"},
+		},
+		"missing-key": {
+			program:       []string{"echo", "{{ .value }}"},
+			query:         map[string]string{},
+			expectedError: true,
+		},
+		"invalid-template": {
+			program:       []string{"echo", "{{ .value "},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"option-value": {
+			program:       []string{"cat", "{{ .file }}"},
+			query:         map[string]string{"file": "--config=/etc/shadow"},
+			expectedError: true,
+		},
+		"option-value-after-flag": {
+			program:       []string{"curl", "-o", "{{ .file }}"},
+			query:         map[string]string{"file": "-o/tmp/x"},
+			expectedError: true,
+		},
+		"option-value-end-of-options": {
+			program:  []string{"cat", "--", "{{ .file }}"},
+			query:    map[string]string{"file": "--config=/etc/shadow"},
+			expected: []string{"cat", "--", "--config=/etc/shadow"},
+		},
+		"option-value-within-option": {
+			program:  []string{"echo", "--value={{ .value }}"},
+			query:    map[string]string{"value": "-1"},
+			expected: []string{"echo", "--value=-1"},
+		},
+		"option-configured": {
+			program:  []string{"ls", "-{{ .flags }}"},
+			query:    map[string]string{"flags": "la"},
+			expected: []string{"ls", "-la"},
+		},
+		"condition": {
+			program:       []string{"ls", "{{ if .all }}-a{{ end }}"},
+			query:         map[string]string{"all": "true"},
+			expectedError: true,
+		},
+		"range": {
+			program:       []string{"echo", "{{ range . }}{{ . }}{{ end }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"function": {
+			program:       []string{"echo", "{{ printf \"%s\" .value }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"pipeline": {
+			program:       []string{"echo", "{{ .value | print }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"variable": {
+			program:       []string{"echo", "{{ $v := .value }}{{ $v }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"nested-field": {
+			program:       []string{"echo", "{{ .value.length }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"define": {
+			program:       []string{"echo", "{{ define \"x\" }}a{{ end }}"},
+			query:         map[string]string{"value": "a"},
+			expectedError: true,
+		},
+		"nul-value": {
+			program:       []string{"echo", "{{ .value }}"},
+			query:         map[string]string{"value": "a\x00b"},
+			expectedError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := expandArguments(testCase.program, testCase.query)
+
+			if (err != nil) != testCase.expectedError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRunProgram_QueryDelivery(t *testing.T) {
+	t.Parallel()
+
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		invocation programInvocation
+		expected   map[string]string
+	}{
+		"stdin": {
+			invocation: programInvocation{
+				Program: []string{programPath},
+				Query:   map[string]string{"value": "pizza"},
+			},
+			expected: map[string]string{"result": "yes", "query_value": "pizza", "value": "pizza"},
+		},
+		"environment": {
+			invocation: programInvocation{
+				Program:       []string{programPath},
+				Query:         map[string]string{"value": "pizza"},
+				QueryDelivery: queryDeliveryEnvironment,
+			},
+			expected: map[string]string{"result": "yes", "query_value": "pizza", "value": "pizza"},
+		},
+		"arguments": {
+			invocation: programInvocation{
+				Program:       []string{programPath, "{{ .value }}"},
+				Query:         map[string]string{"value": "pizza"},
+				QueryDelivery: queryDeliveryArguments,
+			},
+			expected: map[string]string{"result": "yes", "argument": "pizza"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testCase.invocation.AttributePath = path.Root("program")
+
+			n := NewExternalDataSource().(*externalDataSource)
+
+			resultJson, _, diags := n.runProgram(context.Background(), testCase.invocation)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			var got map[string]string
+
+			if err := json.Unmarshal(resultJson, &got); err != nil {
+				t.Fatalf("unexpected error decoding %q: %s", resultJson, err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// redactedValue replaces sensitive values in diagnostics, matching the
+// replacement of values masked by tflog.
+const redactedValue = "***"
+
+// redactor masks sensitive values in logs and diagnostics.
+type redactor struct {
+	// values are masked wherever they appear.
+	values []string
+
+	// patterns are regular expressions whose matches are masked.
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns a redactor of the values of the sensitive query keys and
+// of the matches of the patterns.
+func newRedactor(query map[string]string, sensitiveKeys []string, patterns []*regexp.Regexp) *redactor {
+	r := &redactor{patterns: patterns}
+
+	for _, key := range sensitiveKeys {
+		if value := query[key]; value != "" {
+			r.values = append(r.values, value)
+		}
+	}
+
+	// Longer values are replaced first, so values containing other values
+	// are not partially revealed.
+	sort.Slice(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+
+	return r
+}
+
+// mask returns the context with the sensitive values masked in log messages
+// and fields.
+func (r *redactor) mask(ctx context.Context) context.Context {
+	if len(r.values) > 0 {
+		ctx = tflog.MaskLogStrings(ctx, r.values...)
+	}
+
+	if len(r.patterns) > 0 {
+		ctx = tflog.MaskLogRegexes(ctx, r.patterns...)
+	}
+
+	return ctx
+}
+
+// redact returns the string with the sensitive values masked.
+func (r *redactor) redact(s string) string {
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, redactedValue)
+	}
+
+	for _, pattern := range r.patterns {
+		s = pattern.ReplaceAllString(s, redactedValue)
+	}
+
+	return s
+}
+
+// redactAuditRecord returns the audit record with the sensitive values masked
+// in the program, its arguments and its working directory.
+func (r *redactor) redactAuditRecord(record auditRecord) auditRecord {
+	record.Program = r.redact(record.Program)
+	record.WorkingDir = r.redact(record.WorkingDir)
+
+	args := make([]string, len(record.Args))
+
+	for i, arg := range record.Args {
+		args[i] = r.redact(arg)
+	}
+
+	record.Args = args
+
+	return record
+}
+
+// redactDiagnostics returns the diagnostics with the sensitive values masked
+// in their summaries and details, such as the standard error of programs.
+func (r *redactor) redactDiagnostics(diags diag.Diagnostics) diag.Diagnostics {
+	if len(r.values) == 0 && len(r.patterns) == 0 {
+		return diags
+	}
+
+	redacted := make(diag.Diagnostics, 0, len(diags))
+
+	for _, d := range diags {
+		summary := r.redact(d.Summary())
+		detail := r.redact(d.Detail())
+
+		withPath, ok := d.(diag.DiagnosticWithPath)
+
+		switch {
+		case ok && d.Severity() == diag.SeverityError:
+			redacted = append(redacted, diag.NewAttributeErrorDiagnostic(withPath.Path(), summary, detail))
+		case ok:
+			redacted = append(redacted, diag.NewAttributeWarningDiagnostic(withPath.Path(), summary, detail))
+		case d.Severity() == diag.SeverityError:
+			redacted = append(redacted, diag.NewErrorDiagnostic(summary, detail))
+		default:
+			redacted = append(redacted, diag.NewWarningDiagnostic(summary, detail))
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,153 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	t.Parallel()
+
+	query := map[string]string{
+		"token":   "s3cr3t",
+		"prefix":  "s3c",
+		"region":  "us-east-1",
+		"missing": "",
+	}
+
+	testCases := map[string]struct {
+		sensitiveKeys []string
+		patterns      []*regexp.Regexp
+		input         string
+		expected      string
+	}{
+		"none": {
+			input:    "token s3cr3t in us-east-1",
+			expected: "token s3cr3t in us-east-1",
+		},
+		"sensitive-keys": {
+			sensitiveKeys: []string{"token", "missing", "unknown"},
+			input:         "token s3cr3t in us-east-1",
+			expected:      "token *** in us-east-1",
+		},
+		"sensitive-keys-longest-first": {
+			sensitiveKeys: []string{"prefix", "token"},
+			input:         "token s3cr3t",
+			expected:      "token ***",
+		},
+		"patterns": {
+			patterns: []*regexp.Regexp{regexp.MustCompile(`ghp_[A-Za-z0-9]+`)},
+			input:    "using ghp_abc123 and ghp_def456",
+			expected: "using *** and ***",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newRedactor(query, testCase.sensitiveKeys, testCase.patterns)
+
+			if got := r.redact(testCase.input); got != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactor_RedactDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	r := newRedactor(map[string]string{"token": "s3cr3t"}, []string{"token"}, nil)
+
+	diags := diag.Diagnostics{
+		diag.NewAttributeErrorDiagnostic(path.Root("program"), "Failed", "Error Message: invalid token s3cr3t"),
+		diag.NewWarningDiagnostic("Warning s3cr3t", "Detail"),
+	}
+
+	expected := diag.Diagnostics{
+		diag.NewAttributeErrorDiagnostic(path.Root("program"), "Failed", "Error Message: invalid token ***"),
+		diag.NewWarningDiagnostic("Warning ***", "Detail"),
+	}
+
+	if got := r.redactDiagnostics(diags); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRedactor_RedactAuditRecord(t *testing.T) {
+	t.Parallel()
+
+	r := newRedactor(map[string]string{"token": "s3cr3t"}, []string{"token"}, []*regexp.Regexp{regexp.MustCompile(`ghp_[A-Za-z0-9]+`)})
+
+	record := auditRecord{
+		Program:    "http://127.0.0.1:8080/query?token=s3cr3t",
+		Args:       []string{"--auth", "ghp_abc123", "--user", "s3cr3t"},
+		WorkingDir: "/work",
+		QueryKeys:  []string{"token"},
+	}
+
+	expected := auditRecord{
+		Program:    "http://127.0.0.1:8080/query?token=***",
+		Args:       []string{"--auth", "***", "--user", "***"},
+		WorkingDir: "/work",
+		QueryKeys:  []string{"token"},
+	}
+
+	if diff := cmp.Diff(expected, r.redactAuditRecord(record)); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+
+	if record.Args[1] != "ghp_abc123" {
+		t.Errorf("expected the original record to be unchanged, got: %v", record.Args)
+	}
+}
+
+func TestRunProgram_RedactedLogs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("this test requires a POSIX shell")
+	}
+
+	var output bytes.Buffer
+
+	query := map[string]string{"token": "s3cr3t"}
+	r := newRedactor(query, []string{"token"}, []*regexp.Regexp{regexp.MustCompile(`ghp_[A-Za-z0-9]+`)})
+
+	ctx := r.mask(tflogtest.RootLogger(context.Background(), &output))
+
+	n := NewExternalDataSource().(*externalDataSource)
+
+	invocation := programInvocation{
+		Program:       []string{"/bin/sh", "-c", `echo "using $1 and ghp_abc123" >&2; echo '{"token":"s3cr3t"}'`, "sh", "s3cr3t"},
+		Query:         query,
+		AttributePath: path.Root("program"),
+	}
+
+	if _, _, diags := n.runProgram(ctx, invocation); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	logs := output.String()
+
+	if !strings.Contains(logs, "Executed external program") {
+		t.Fatalf("expected trace logs, got: %s", logs)
+	}
+
+	for _, sensitive := range []string{"s3cr3t", "ghp_abc123"} {
+		if strings.Contains(logs, sensitive) {
+			t.Errorf("expected %q to be masked, got: %s", sensitive, logs)
+		}
+	}
+}
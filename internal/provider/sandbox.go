@@ -0,0 +1,285 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// sandboxShimEnv is the environment variable containing the JSON encoded
+// sandbox of a program when the provider is re-executed to set up the sandbox
+// before executing the program.
+const sandboxShimEnv = "TF_EXTERNAL_SANDBOX"
+
+// sandboxConfig describes the restrictions applied to a program by the shim
+// before executing it.
+type sandboxConfig struct {
+	// Namespaces starts the program in new user, mount, PID and, unless
+	// Network is set, network namespaces, with a read-only root filesystem,
+	// a private /tmp and a writable working directory. This is only
+	// supported on Linux.
+	Namespaces bool `json:"namespaces,omitempty"`
+
+	// Network keeps the network of the provider available to the program.
+	Network bool `json:"network,omitempty"`
+
+	// Landlock restricts the filesystem access of the program, if not nil.
+	Landlock *landlockConfig `json:"landlock,omitempty"`
+
+	// Seccomp is the seccomp profile installed before executing the program,
+	// if not nil. This is only supported on Linux on amd64 and arm64.
+	Seccomp *seccompConfig `json:"seccomp,omitempty"`
+
+	// Limits are the resource limits of the program, if not nil. This is
+	// only supported on Linux.
+	Limits *limitsConfig `json:"limits,omitempty"`
+
+	// Credential is the user and group the shim switches to before executing
+	// the program, if not nil.
+	Credential *programCredential `json:"credential,omitempty"`
+
+	// Program is the resolved path of the program, executed by the shim
+	// once the sandbox is set up.
+	Program string `json:"program"`
+
+	// WorkingDir is the absolute path of the working directory of the
+	// program.
+	WorkingDir string `json:"working_dir"`
+
+	// ReadOnlyPaths remain readable by the program, even if they are within
+	// /tmp, such as the file of a script.
+	ReadOnlyPaths []string `json:"read_only_paths,omitempty"`
+}
+
+// landlockConfig describes the paths a program restricted with Landlock can
+// access, in addition to the program itself.
+type landlockConfig struct {
+	ReadPaths  []string `json:"read_paths,omitempty"`
+	WritePaths []string `json:"write_paths,omitempty"`
+}
+
+type externalSandboxModel struct {
+	Network types.Bool `tfsdk:"network"`
+}
+
+type providerSandboxModel struct {
+	Required     types.Bool `tfsdk:"required"`
+	AllowNetwork types.Bool `tfsdk:"allow_network"`
+}
+
+// sandbox returns the sandbox of the program of the data source, if any. The
+// provider can require every program to run in namespaces.
+func (n *externalDataSource) sandbox(ctx context.Context, config externalDataSourceModelV0) (*sandboxConfig, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// Endpoints are not executed by the provider.
+	if !config.Endpoint.IsNull() {
+		return nil, diags
+	}
+
+	sandbox := &sandboxConfig{}
+
+	if config.Sandbox != nil || n.providerData.SandboxRequired {
+		if !config.Plugin.IsNull() {
+			diags.AddAttributeError(
+				path.Root("plugin"),
+				"Sandbox Required",
+				"The provider is configured to require every program to run in a sandbox, which is not supported by plugins.",
+			)
+			return nil, diags
+		}
+
+		if !sandboxSupported {
+			diags.AddAttributeError(
+				path.Root("sandbox"),
+				"Unsupported Sandbox",
+				"Programs can only be run in a sandbox on Linux."+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+			)
+			return nil, diags
+		}
+
+		sandbox.Namespaces = true
+
+		if config.Sandbox != nil {
+			sandbox.Network = config.Sandbox.Network.ValueBool()
+		}
+
+		if sandbox.Network && n.providerData.SandboxNetworkDenied {
+			diags.AddAttributeError(
+				path.Root("sandbox").AtName("network"),
+				"Sandbox Network Not Allowed",
+				"The provider is configured to deny network access to sandboxed programs. "+
+					"Remove the network attribute or verify the program requires network access with the provider operators.",
+			)
+			return nil, diags
+		}
+	}
+
+	if !config.AllowedReadPaths.IsNull() || !config.AllowedWritePaths.IsNull() {
+		landlock := &landlockConfig{}
+
+		diags.Append(config.AllowedReadPaths.ElementsAs(ctx, &landlock.ReadPaths, false)...)
+		diags.Append(config.AllowedWritePaths.ElementsAs(ctx, &landlock.WritePaths, false)...)
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		attributePath := path.Root("allowed_read_paths")
+
+		if config.AllowedReadPaths.IsNull() {
+			attributePath = path.Root("allowed_write_paths")
+		}
+
+		if abi, err := landlockABI(); err != nil {
+			diags.AddAttributeWarning(
+				attributePath,
+				"Landlock Unavailable",
+				"The program is executed without filesystem restrictions, since Landlock is not supported by this platform "+
+					"or is disabled in the kernel. Landlock requires Linux 5.13 or later with Landlock enabled in the list "+
+					"of security modules."+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS)+
+					fmt.Sprintf("\nError: %s", err),
+			)
+		} else {
+			tflog.Debug(ctx, "Restricting external program with Landlock", map[string]interface{}{"abi": abi})
+
+			sandbox.Landlock = landlock
+		}
+	}
+
+	if !config.SeccompProfile.IsNull() {
+		if !seccompSupported {
+			diags.AddAttributeError(
+				path.Root("seccomp_profile"),
+				"Unsupported Seccomp Profile",
+				"Seccomp profiles are only supported on Linux on amd64 and arm64."+
+					fmt.Sprintf("\n\nPlatform: %s/%s", runtime.GOOS, runtime.GOARCH),
+			)
+			return nil, diags
+		}
+
+		seccomp, err := loadSeccompProfile(config.SeccompProfile.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("seccomp_profile"),
+				"Invalid Seccomp Profile",
+				"The seccomp profile could not be loaded. It must be either no-network, no-exec or the path of "+
+					"a JSON profile."+
+					fmt.Sprintf("\n\nProfile: %s", config.SeccompProfile.ValueString())+
+					fmt.Sprintf("\nError: %s", err),
+			)
+			return nil, diags
+		}
+
+		sandbox.Seccomp = seccomp
+	}
+
+	if config.Limits != nil {
+		if !limitsSupported {
+			diags.AddAttributeError(
+				path.Root("limits"),
+				"Unsupported Limits",
+				"Resource limits can only be applied to programs on Linux."+
+					fmt.Sprintf("\n\nPlatform: %s", runtime.GOOS),
+			)
+			return nil, diags
+		}
+
+		sandbox.Limits = config.Limits.config()
+	}
+
+	if !sandbox.Namespaces && sandbox.Landlock == nil && sandbox.Seccomp == nil && sandbox.Limits == nil {
+		return nil, diags
+	}
+
+	return sandbox, diags
+}
+
+// RunSandboxShim executes the program of a sandbox if the process is a
+// re-executed provider setting up the sandbox, and otherwise returns
+// immediately. It must be called at the start of main, before any other
+// goroutine is started.
+func RunSandboxShim() {
+	encoded, ok := os.LookupEnv(sandboxShimEnv)
+	if !ok {
+		return
+	}
+
+	// Capabilities and Landlock restrictions apply to the calling thread,
+	// which must be the thread executing the program.
+	runtime.LockOSThread()
+
+	var config sandboxConfig
+
+	err := json.Unmarshal([]byte(encoded), &config)
+
+	if err == nil {
+		err = runSandboxShim(config, sandboxEnvironment(os.Environ()))
+	}
+
+	// The shim only returns if the program could not be executed.
+	fmt.Fprintf(os.Stderr, "unable to set up sandbox: %s\n", err)
+	os.Exit(126)
+}
+
+// sandboxEnvironment returns the environment of the program executed by the
+// shim, which is the environment of the shim without its configuration.
+func sandboxEnvironment(environ []string) []string {
+	environment := make([]string, 0, len(environ))
+
+	for _, variable := range environ {
+		if !strings.HasPrefix(variable, sandboxShimEnv+"=") {
+			environment = append(environment, variable)
+		}
+	}
+
+	return environment
+}
+
+// applySandbox modifies the command to execute the provider as a shim which
+// sets up the sandbox before executing the program of the command. Errors
+// are returned when the command is started.
+func applySandbox(cmd *exec.Cmd, sandbox *sandboxConfig) {
+	if sandbox == nil || cmd.Err != nil {
+		return
+	}
+
+	config := *sandbox
+	config.Program = cmd.Path
+
+	workingDir, err := filepath.Abs(cmd.Dir)
+	if err != nil {
+		cmd.Err = fmt.Errorf("unable to determine working directory: %w", err)
+		return
+	}
+
+	config.WorkingDir = workingDir
+
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		cmd.Err = err
+		return
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	cmd.Env = append(sandboxEnvironment(cmd.Env), sandboxShimEnv+"="+string(encoded))
+
+	cmd.Err = sandboxCommand(cmd, config)
+}
@@ -0,0 +1,411 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxSupported is whether programs can be sandboxed on this platform.
+const sandboxSupported = true
+
+const (
+	// sandboxShimPath is the path of the executable of the provider, which
+	// remains valid if the provider is replaced on disk.
+	sandboxShimPath = "/proc/self/exe"
+
+	// secureBits prevent the program from regaining capabilities by
+	// executing files as the root user of the user namespace, and lock this
+	// setting. The values are from linux/securebits.h.
+	secureBits = 1<<0 | 1<<1 | // SECBIT_NOROOT, SECBIT_NOROOT_LOCKED
+		secureBitNoSetuidFixup | 1<<3 | // SECBIT_NO_SETUID_FIXUP_LOCKED
+		1<<5 | // SECBIT_KEEP_CAPS_LOCKED
+		1<<6 | 1<<7 // SECBIT_NO_CAP_AMBIENT_RAISE, SECBIT_NO_CAP_AMBIENT_RAISE_LOCKED
+
+	// secureBitNoSetuidFixup is SECBIT_NO_SETUID_FIXUP, which keeps the
+	// capabilities of a process when switching from the root user.
+	secureBitNoSetuidFixup = 1 << 2
+)
+
+// sandboxCommand modifies the command to start the shim in new namespaces.
+// The arguments of the command are kept, so the program receives them
+// unchanged once executed by the shim.
+func sandboxCommand(cmd *exec.Cmd, config sandboxConfig) error {
+	cmd.Path = sandboxShimPath
+
+	if !config.Namespaces {
+		return nil
+	}
+
+	cloneflags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID)
+
+	if !config.Network {
+		cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	// The user and group of the provider are mapped to themselves, so files
+	// created by the program have the expected owner.
+	uidMappings := []syscall.SysProcIDMap{
+		{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+	}
+
+	gidMappings := []syscall.SysProcIDMap{
+		{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+	}
+
+	// The shim switches to the credential within the namespace, after
+	// setting up the sandbox as the user of the provider.
+	if credential := config.Credential; credential != nil {
+		if int(credential.Uid) != os.Getuid() {
+			uidMappings = append(uidMappings, syscall.SysProcIDMap{ContainerID: int(credential.Uid), HostID: int(credential.Uid), Size: 1})
+		}
+
+		if int(credential.Gid) != os.Getgid() {
+			gidMappings = append(gidMappings, syscall.SysProcIDMap{ContainerID: int(credential.Gid), HostID: int(credential.Gid), Size: 1})
+		}
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  cloneflags,
+		UidMappings: uidMappings,
+		GidMappings: gidMappings,
+		// The shim drops the supplementary groups of the provider when
+		// switching to the credential.
+		GidMappingsEnableSetgroups: config.Credential != nil,
+	}
+
+	return nil
+}
+
+// runSandboxShim sets up the sandbox and executes the program. It only
+// returns if an error occurs.
+func runSandboxShim(config sandboxConfig, environment []string) error {
+	if config.Namespaces {
+		if err := setupMounts(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Credential != nil {
+		// The capabilities within the user namespace are still required to
+		// drop the capabilities, so they are kept when switching users.
+		if config.Namespaces {
+			if err := unix.Prctl(unix.PR_SET_SECUREBITS, secureBitNoSetuidFixup, 0, 0, 0); err != nil {
+				return fmt.Errorf("unable to set secure bits: %w", err)
+			}
+		}
+
+		if err := switchCredential(config.Credential); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Chdir(config.WorkingDir); err != nil {
+		return err
+	}
+
+	program := config.Program
+
+	if !filepath.IsAbs(program) {
+		program = filepath.Join(config.WorkingDir, program)
+	}
+
+	if config.Landlock != nil {
+		readPaths := append([]string{program}, config.ReadOnlyPaths...)
+
+		if err := restrictFilesystem(append(readPaths, config.Landlock.ReadPaths...), config.Landlock.WritePaths); err != nil {
+			return err
+		}
+	}
+
+	if config.Namespaces {
+		if err := dropCapabilities(); err != nil {
+			return err
+		}
+	}
+
+	if config.Limits != nil {
+		if err := setLimits(config.Limits); err != nil {
+			return err
+		}
+	}
+
+	if config.Seccomp == nil {
+		return unix.Exec(program, os.Args, environment)
+	}
+
+	return execWithSeccomp(config.Seccomp, program, os.Args, environment)
+}
+
+// execWithSeccomp installs the seccomp profile and executes the program. The
+// profile must be installed last, as it can deny the system calls used to
+// set up the sandbox, and the program is executed with the path allowed by
+// the no-exec profile.
+func execWithSeccomp(config *seccompConfig, program string, args []string, environment []string) error {
+	argv0, err := unix.BytePtrFromString(program)
+	if err != nil {
+		return err
+	}
+
+	execPath := uintptr(unsafe.Pointer(argv0))
+
+	if config.Name == seccompProfileNoExec {
+		execPath, err = mapExecPath(program)
+		if err != nil {
+			return err
+		}
+	}
+
+	argv, err := syscall.SlicePtrFromStrings(args)
+	if err != nil {
+		return err
+	}
+
+	envv, err := syscall.SlicePtrFromStrings(environment)
+	if err != nil {
+		return err
+	}
+
+	if err := installSeccomp(config, execPath); err != nil {
+		return err
+	}
+
+	_, _, errno := unix.RawSyscall(unix.SYS_EXECVE, execPath, uintptr(unsafe.Pointer(&argv[0])), uintptr(unsafe.Pointer(&envv[0])))
+
+	runtime.KeepAlive(argv0)
+
+	return errno
+}
+
+// switchCredential switches the shim to the user and group of the
+// credential without supplementary groups. The system calls of the syscall package apply to every thread of the shim.
+func switchCredential(credential *programCredential) error {
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("unable to drop supplementary groups: %w", err)
+	}
+
+	if err := syscall.Setresgid(int(credential.Gid), int(credential.Gid), int(credential.Gid)); err != nil {
+		return fmt.Errorf("unable to switch to group %d: %w", credential.Gid, err)
+	}
+
+	if err := syscall.Setresuid(int(credential.Uid), int(credential.Uid), int(credential.Uid)); err != nil {
+		return fmt.Errorf("unable to switch to user %d: %w", credential.Uid, err)
+	}
+
+	return nil
+}
+
+// setupMounts makes the root filesystem read-only except for the working
+// directory, replaces /tmp and /proc, and keeps the read-only paths visible.
+func setupMounts(config sandboxConfig) error {
+	// Mounts must not propagate back to the namespace of the provider.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("unable to make mounts private: %w", err)
+	}
+
+	type bindMount struct {
+		path     string
+		fd       int
+		readOnly bool
+	}
+
+	var binds []bindMount
+
+	// Copies of the paths remaining visible are taken before the root is
+	// made read-only and /tmp is replaced, and attached again afterwards.
+	for _, path := range append([]string{config.WorkingDir}, config.ReadOnlyPaths...) {
+		fd, err := unix.OpenTree(unix.AT_FDCWD, path, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+		if err != nil {
+			return fmt.Errorf("unable to copy mount of %s: %w", path, err)
+		}
+
+		binds = append(binds, bindMount{path: path, fd: fd, readOnly: len(binds) > 0})
+	}
+
+	err := unix.MountSetattr(-1, "/", unix.AT_RECURSIVE, &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY})
+	if err != nil {
+		return fmt.Errorf("unable to make root read-only, which requires Linux 5.12 or later: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("unable to mount private /tmp: %w", err)
+	}
+
+	for _, bind := range binds {
+		if bind.readOnly {
+			err := unix.MountSetattr(bind.fd, "", unix.AT_EMPTY_PATH|unix.AT_RECURSIVE, &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY})
+			if err != nil {
+				return fmt.Errorf("unable to make %s read-only: %w", bind.path, err)
+			}
+		}
+
+		// Mount points within the private /tmp do not exist yet.
+		if err := os.MkdirAll(bind.path, 0o700); err != nil {
+			return fmt.Errorf("unable to create mount point %s: %w", bind.path, err)
+		}
+
+		if err := unix.MoveMount(bind.fd, "", unix.AT_FDCWD, bind.path, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+			return fmt.Errorf("unable to mount %s: %w", bind.path, err)
+		}
+
+		_ = unix.Close(bind.fd)
+	}
+
+	// The shim is the first process of the new PID namespace, so processes
+	// outside of the sandbox are hidden with a new instance of /proc.
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("unable to mount /proc: %w", err)
+	}
+
+	return nil
+}
+
+// dropCapabilities removes the capabilities the shim holds within its user
+// namespace, so the program cannot undo the sandbox, such as by remounting
+// the root filesystem as writable.
+func dropCapabilities() error {
+	if err := unix.Prctl(unix.PR_SET_SECUREBITS, secureBits, 0, 0, 0); err != nil {
+		return fmt.Errorf("unable to set secure bits: %w", err)
+	}
+
+	for capability := 0; capability <= unix.CAP_LAST_CAP; capability++ {
+		// Capabilities unknown to the kernel cannot be dropped.
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("unable to drop capability %d: %w", capability, err)
+		}
+	}
+
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return fmt.Errorf("unable to drop capabilities: %w", err)
+	}
+
+	return nil
+}
+
+const (
+	// landlockReadAccess is the access to allowed read paths.
+	landlockReadAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	// landlockFileAccess is the access which applies to files rather than
+	// directories.
+	landlockFileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+// landlockABI returns the version of the Landlock ABI supported by the
+// kernel, or an error if Landlock is unavailable.
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(abi), nil
+}
+
+// landlockHandledAccess returns the filesystem access restricted by the given
+// version of the Landlock ABI.
+func landlockHandledAccess(abi int) uint64 {
+	// The access of the first version of the ABI.
+	access := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+
+	return access
+}
+
+// restrictFilesystem restricts the filesystem access of the process and the
+// programs it executes to the given paths with Landlock.
+func restrictFilesystem(readPaths []string, writePaths []string) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return fmt.Errorf("unable to determine Landlock ABI: %w", err)
+	}
+
+	handled := landlockHandledAccess(abi)
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+
+	ruleset, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("unable to create Landlock ruleset: %w", errno)
+	}
+
+	defer unix.Close(int(ruleset))
+
+	addRule := func(path string, access uint64) error {
+		fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("unable to open allowed path %s: %w", path, err)
+		}
+
+		defer unix.Close(fd)
+
+		var stat unix.Stat_t
+
+		if err := unix.Fstat(fd, &stat); err != nil {
+			return fmt.Errorf("unable to stat allowed path %s: %w", path, err)
+		}
+
+		if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+			access &= landlockFileAccess
+		}
+
+		rule := unix.LandlockPathBeneathAttr{Allowed_access: access & handled, Parent_fd: int32(fd)}
+
+		_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, ruleset, unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("unable to allow path %s: %w", path, errno)
+		}
+
+		return nil
+	}
+
+	for _, path := range readPaths {
+		if err := addRule(path, landlockReadAccess); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range writePaths {
+		if err := addRule(path, handled); err != nil {
+			return err
+		}
+	}
+
+	// Landlock requires the process to not gain privileges, such as by
+	// executing set-user-ID programs.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("unable to set no new privileges: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, ruleset, 0, 0); errno != 0 {
+		return fmt.Errorf("unable to restrict filesystem access with Landlock: %w", errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestRunProgram_Sandbox(t *testing.T) {
+	// Unprivileged user namespaces are disabled on some distributions.
+	probe := programInvocation{Program: []string{"/bin/true"}}
+	cmd := probe.command(context.Background())
+
+	applySandbox(cmd, &sandboxConfig{Namespaces: true})
+
+	if err := cmd.Run(); err != nil {
+		t.Skipf("this test requires user namespaces: %s", err)
+	}
+
+	hostNetDev, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first two lines of /proc/net/dev are headers.
+	hostInterfaces := strconv.Itoa(strings.Count(string(hostNetDev), "\n") - 2)
+
+	workingDir := t.TempDir()
+	exposedDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(exposedDir, "exposed"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hostFile := filepath.Join(os.TempDir(), "tf-acc-external-sandbox-host")
+
+	if err := os.WriteFile(hostFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.Remove(hostFile) })
+
+	script := `
+pid=$$
+interfaces=$(tail -n +3 /proc/net/dev | wc -l)
+if touch /etc/tf-acc-external-sandbox 2>/dev/null; then root=writable; else root=read-only; fi
+if touch written 2>/dev/null; then working_dir=writable; else working_dir=read-only; fi
+if [ -e "$1" ]; then tmp=shared; else tmp=private; fi
+if mount -o remount,rw / 2>/dev/null; then remount=allowed; else remount=denied; fi
+if [ ! -e "$2/exposed" ]; then exposed=hidden; elif touch "$2/exposed" 2>/dev/null; then exposed=writable; else exposed=read-only; fi
+printf '{"pid":"%s","interfaces":"%s","root":"%s","working_dir":"%s","tmp":"%s","remount":"%s","exposed":"%s"}' "$pid" $interfaces "$root" "$working_dir" "$tmp" "$remount" "$exposed"
+`
+
+	testCases := map[string]struct {
+		sandbox  *sandboxConfig
+		expected map[string]string
+	}{
+		"default": {
+			sandbox: &sandboxConfig{Namespaces: true},
+			expected: map[string]string{
+				"pid":         "1",
+				"interfaces":  "1",
+				"root":        "read-only",
+				"working_dir": "writable",
+				"tmp":         "private",
+				"remount":     "denied",
+				"exposed":     "hidden",
+			},
+		},
+		"read-only-paths": {
+			sandbox: &sandboxConfig{Namespaces: true, ReadOnlyPaths: []string{exposedDir}},
+			expected: map[string]string{
+				"pid":         "1",
+				"interfaces":  "1",
+				"root":        "read-only",
+				"working_dir": "writable",
+				"tmp":         "private",
+				"remount":     "denied",
+				"exposed":     "read-only",
+			},
+		},
+		"network": {
+			sandbox: &sandboxConfig{Namespaces: true, Network: true},
+			expected: map[string]string{
+				"pid":         "1",
+				"interfaces":  hostInterfaces,
+				"root":        "read-only",
+				"working_dir": "writable",
+				"tmp":         "private",
+				"remount":     "denied",
+				"exposed":     "hidden",
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:       []string{"/bin/sh", "-c", script, "sh", hostFile, exposedDir},
+				WorkingDir:    workingDir,
+				AttributePath: path.Root("program"),
+				Sandbox:       testCase.sandbox,
+			}
+
+			resultJson, programPath, diags := n.runProgram(context.Background(), invocation)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if programPath != "/bin/sh" {
+				t.Errorf("expected program path /bin/sh, got: %s", programPath)
+			}
+
+			var got map[string]string
+
+			if err := json.Unmarshal(resultJson, &got); err != nil {
+				t.Fatalf("unexpected error decoding %q: %s", resultJson, err)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+
+			if _, err := os.Stat(filepath.Join(workingDir, "written")); err != nil {
+				t.Errorf("expected file written in working directory: %s", err)
+			}
+		})
+	}
+}
+
+func TestRunProgram_Landlock(t *testing.T) {
+	if _, err := landlockABI(); err != nil {
+		t.Skipf("this test requires Landlock: %s", err)
+	}
+
+	readDir := t.TempDir()
+	writeDir := t.TempDir()
+	deniedDir := t.TempDir()
+
+	for _, dir := range []string{readDir, writeDir, deniedDir} {
+		if err := os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := `
+check() {
+  if cat "$1/file" >/dev/null 2>&1; then read=yes; else read=no; fi
+  if echo written 2>/dev/null >"$1/file"; then write=yes; else write=no; fi
+  printf '%s/%s' "$read" "$write"
+}
+printf '{"read":"%s","write":"%s","denied":"%s"}' "$(check "$1")" "$(check "$2")" "$(check "$3")"
+`
+
+	n := NewExternalDataSource().(*externalDataSource)
+
+	invocation := programInvocation{
+		Program:       []string{"/bin/sh", "-c", script, "sh", readDir, writeDir, deniedDir},
+		AttributePath: path.Root("program"),
+		Sandbox: &sandboxConfig{
+			Landlock: &landlockConfig{
+				// The shell depends on shared libraries and /dev/null.
+				ReadPaths:  []string{"/bin", "/usr", "/lib", "/lib64", "/etc", readDir},
+				WritePaths: []string{"/dev/null", writeDir},
+			},
+		},
+	}
+
+	resultJson, _, diags := n.runProgram(context.Background(), invocation)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	var got map[string]string
+
+	if err := json.Unmarshal(resultJson, &got); err != nil {
+		t.Fatalf("unexpected error decoding %q: %s", resultJson, err)
+	}
+
+	expected := map[string]string{
+		"read":   "yes/no",
+		"write":  "yes/yes",
+		"denied": "no/no",
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected difference: %s", diff)
+	}
+}
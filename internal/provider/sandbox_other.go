@@ -0,0 +1,30 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !linux
+
+package provider
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// sandboxSupported is whether programs can be sandboxed on this platform.
+const sandboxSupported = false
+
+// errSandboxUnsupported is returned when starting a sandboxed program, which
+// is prevented by validation on platforms other than Linux.
+var errSandboxUnsupported = errors.New("sandboxing programs is only supported on Linux")
+
+func sandboxCommand(cmd *exec.Cmd, config sandboxConfig) error {
+	return errSandboxUnsupported
+}
+
+func runSandboxShim(config sandboxConfig, environment []string) error {
+	return errSandboxUnsupported
+}
+
+func landlockABI() (int, error) {
+	return 0, errors.New("Landlock is only supported on Linux")
+}
@@ -0,0 +1,56 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// scriptFilename is the name of the file inline scripts are written to.
+const scriptFilename = "script"
+
+// materializeScript writes an inline script to a file in a new temporary
+// directory, which is only accessible to the current user, and returns the
+// path of the file along with a function removing the directory.
+func materializeScript(script string) (string, func(), error) {
+	dir, cleanup, err := privateTempDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	scriptPath := filepath.Join(dir, scriptFilename)
+
+	// The script is executable so it can be run directly using its
+	// interpreter directive when no interpreter is configured.
+	if err := os.WriteFile(scriptPath, []byte(script), 0o700); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return scriptPath, cleanup, nil
+}
+
+// privateTempDir creates a new temporary directory, which is only accessible
+// to the current user, and returns its path along with a function removing
+// it.
+func privateTempDir() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "terraform-provider-external-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	// MkdirTemp creates the directory with mode 0700, however this is
+	// enforced in case of an unusual umask.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return dir, cleanup, nil
+}
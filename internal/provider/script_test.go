@@ -0,0 +1,55 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMaterializeScript(t *testing.T) {
+	t.Parallel()
+
+	scriptPath, cleanup, err := materializeScript("#!/bin/sh\necho '{}'\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	content, err := os.ReadFile(scriptPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got, expected := string(content), "#!/bin/sh\necho '{}'\n"; got != expected {
+		t.Errorf("expected content %q, got %q", expected, got)
+	}
+
+	if runtime.GOOS != "windows" {
+		dirInfo, err := os.Stat(filepath.Dir(scriptPath))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if got := dirInfo.Mode().Perm(); got != 0o700 {
+			t.Errorf("expected directory mode 0700, got %o", got)
+		}
+
+		fileInfo, err := os.Stat(scriptPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if got := fileInfo.Mode().Perm(); got&0o077 != 0 {
+			t.Errorf("expected file to be private, got mode %o", got)
+		}
+	}
+
+	cleanup()
+
+	if _, err := os.Stat(filepath.Dir(scriptPath)); !os.IsNotExist(err) {
+		t.Errorf("expected directory to be removed, got: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// seccompProfileNoNetwork denies the creation of sockets other than
+	// Unix domain sockets.
+	seccompProfileNoNetwork = "no-network"
+
+	// seccompProfileNoExec denies the execution of other programs.
+	seccompProfileNoExec = "no-exec"
+)
+
+// seccompActions are the supported actions of seccomp profiles.
+var seccompActions = map[string]bool{
+	"SCMP_ACT_ALLOW":        true,
+	"SCMP_ACT_ERRNO":        true,
+	"SCMP_ACT_KILL":         true,
+	"SCMP_ACT_KILL_PROCESS": true,
+	"SCMP_ACT_KILL_THREAD":  true,
+	"SCMP_ACT_LOG":          true,
+	"SCMP_ACT_TRAP":         true,
+}
+
+// seccompConfig is the seccomp profile of a program.
+type seccompConfig struct {
+	// Name is the name of a built-in profile or the path of a profile.
+	Name string `json:"name"`
+
+	// Profile is the decoded profile, unless Name is a built-in profile.
+	Profile *seccompProfile `json:"profile,omitempty"`
+}
+
+// seccompProfile is a seccomp profile in the format of the OCI runtime
+// specification, as used by container runtimes. Only rules matching system
+// calls by name are supported.
+type seccompProfile struct {
+	DefaultAction   string               `json:"defaultAction"`
+	DefaultErrnoRet *uint32              `json:"defaultErrnoRet,omitempty"`
+	Syscalls        []seccompSyscallRule `json:"syscalls,omitempty"`
+}
+
+type seccompSyscallRule struct {
+	Names    []string          `json:"names"`
+	Action   string            `json:"action"`
+	ErrnoRet *uint32           `json:"errnoRet,omitempty"`
+	Args     []json.RawMessage `json:"args,omitempty"`
+	Includes json.RawMessage   `json:"includes,omitempty"`
+	Excludes json.RawMessage   `json:"excludes,omitempty"`
+}
+
+// loadSeccompProfile returns the built-in profile with the given name, or
+// reads the profile at the given path.
+func loadSeccompProfile(name string) (*seccompConfig, error) {
+	if name == seccompProfileNoNetwork || name == seccompProfileNoExec {
+		return &seccompConfig{Name: name}, nil
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := parseSeccompProfile(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &seccompConfig{Name: name, Profile: profile}, nil
+}
+
+// parseSeccompProfile decodes and verifies a profile.
+func parseSeccompProfile(content []byte) (*seccompProfile, error) {
+	var profile seccompProfile
+
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&profile); err != nil {
+		return nil, err
+	}
+
+	if profile.DefaultAction == "" {
+		return nil, errors.New("missing defaultAction")
+	}
+
+	if !seccompActions[profile.DefaultAction] {
+		return nil, fmt.Errorf("unsupported defaultAction %q", profile.DefaultAction)
+	}
+
+	for i, rule := range profile.Syscalls {
+		if !seccompActions[rule.Action] {
+			return nil, fmt.Errorf("syscalls[%d]: unsupported action %q", i, rule.Action)
+		}
+
+		// Ignoring conditions would apply the action to more calls than
+		// intended.
+		if len(rule.Args) > 0 {
+			return nil, fmt.Errorf("syscalls[%d]: argument conditions are not supported", i)
+		}
+
+		if len(rule.Includes) > 0 && string(rule.Includes) != "{}" || len(rule.Excludes) > 0 && string(rule.Excludes) != "{}" {
+			return nil, fmt.Errorf("syscalls[%d]: includes and excludes are not supported", i)
+		}
+	}
+
+	return &profile, nil
+}
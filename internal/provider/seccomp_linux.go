@@ -0,0 +1,278 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux && (amd64 || arm64)
+
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompSupported is whether seccomp profiles can be installed on this
+// platform.
+const seccompSupported = true
+
+const (
+	// Offsets of the fields of struct seccomp_data, which is the input of
+	// seccomp filters. Arguments are 64-bit little-endian values.
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArgs = 16
+
+	// seccompX32SyscallBit marks system calls of the x32 ABI on amd64,
+	// which would otherwise bypass rules on system call numbers.
+	seccompX32SyscallBit = 0x40000000
+
+	// seccompViolationAction is the action of the built-in profiles, which
+	// terminates the program so violations can be reported.
+	seccompViolationAction = unix.SECCOMP_RET_KILL_PROCESS
+)
+
+// seccompRule is the action applied to a system call by a seccomp filter.
+type seccompRule struct {
+	syscall uint32
+	action  uint32
+
+	// allowedArg allows calls where the argument at argIndex is equal to
+	// this value instead of applying the action, if not nil.
+	allowedArg *uint64
+	argIndex   int
+}
+
+// seccompExecPathAttempts is the number of random addresses tried by
+// mapExecPath.
+const seccompExecPathAttempts = 16
+
+// mapExecPath copies the path of the program to a read-only page at a random
+// address, which is the only path the no-exec profile allows to execute. The
+// program cannot read the address, as its memory replaces the memory of the
+// shim, and each wrong guess terminates the guessing process. This makes the
+// exception hard to reuse, rather than impossible: a program can start
+// processes which guess until one succeeds.
+func mapExecPath(program string) (uintptr, error) {
+	pageSize := uintptr(os.Getpagesize())
+
+	if uintptr(len(program)) >= pageSize {
+		return 0, fmt.Errorf("program path is longer than %d bytes", pageSize-1)
+	}
+
+	var random [8]byte
+
+	for attempt := 0; attempt < seccompExecPathAttempts; attempt++ {
+		if _, err := rand.Read(random[:]); err != nil {
+			return 0, err
+		}
+
+		hint := seccompExecPathMinAddress + uintptr(binary.LittleEndian.Uint64(random[:])%uint64(seccompExecPathMaxAddress-seccompExecPathMinAddress))
+		hint &^= pageSize - 1
+
+		addr, _, errno := unix.RawSyscall6(unix.SYS_MMAP, hint, pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS|unix.MAP_FIXED_NOREPLACE, ^uintptr(0), 0)
+		if errno != 0 {
+			continue
+		}
+
+		// Kernels before Linux 4.17 treat the address as a hint.
+		if addr != hint {
+			_, _, _ = unix.RawSyscall(unix.SYS_MUNMAP, addr, pageSize, 0)
+			continue
+		}
+
+		page := unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(nil), addr)), pageSize)
+
+		copy(page, program)
+
+		if err := unix.Mprotect(page, unix.PROT_READ); err != nil {
+			return 0, err
+		}
+
+		return addr, nil
+	}
+
+	return 0, errors.New("unable to map the path of the program at a random address")
+}
+
+// installSeccomp installs the seccomp filter of the profile on the current
+// thread. The execve system call with the path at the address execPath
+// remains allowed so the shim can execute the program with the no-exec
+// profile.
+func installSeccomp(config *seccompConfig, execPath uintptr) error {
+	rules, defaultAction, err := seccompRules(config, uint64(execPath))
+	if err != nil {
+		return err
+	}
+
+	filter := seccompFilter(rules, defaultAction)
+
+	program := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// Installing a filter without privileges requires the process to not
+	// gain privileges, such as by executing set-user-ID programs.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("unable to set no new privileges: %w", err)
+	}
+
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return fmt.Errorf("unable to install seccomp profile: %w", err)
+	}
+
+	runtime.KeepAlive(filter)
+
+	return nil
+}
+
+// seccompRules returns the rules and default action of the profile.
+func seccompRules(config *seccompConfig, execPath uint64) ([]seccompRule, uint32, error) {
+	switch config.Name {
+	case seccompProfileNoNetwork:
+		unixDomain := uint64(unix.AF_UNIX)
+
+		return []seccompRule{
+			{syscall: unix.SYS_SOCKET, action: seccompViolationAction, allowedArg: &unixDomain, argIndex: 0},
+			// Sockets can also be created through io_uring.
+			{syscall: unix.SYS_IO_URING_SETUP, action: seccompViolationAction},
+		}, unix.SECCOMP_RET_ALLOW, nil
+	case seccompProfileNoExec:
+		// The path is mapped at a random address by mapExecPath, which
+		// programs must guess to reuse the exception.
+		return []seccompRule{
+			{syscall: unix.SYS_EXECVE, action: seccompViolationAction, allowedArg: &execPath, argIndex: 0},
+			{syscall: unix.SYS_EXECVEAT, action: seccompViolationAction},
+		}, unix.SECCOMP_RET_ALLOW, nil
+	}
+
+	if config.Profile == nil {
+		return nil, 0, fmt.Errorf("unknown seccomp profile %q", config.Name)
+	}
+
+	var rules []seccompRule
+
+	seen := make(map[uint32]bool)
+
+	for _, rule := range config.Profile.Syscalls {
+		action := seccompAction(rule.Action, rule.ErrnoRet)
+
+		for _, name := range rule.Names {
+			syscall, ok := seccompSyscalls[name]
+
+			// Profiles commonly list system calls of every architecture.
+			if !ok || seen[syscall] {
+				continue
+			}
+
+			seen[syscall] = true
+
+			rules = append(rules, seccompRule{syscall: syscall, action: action})
+		}
+	}
+
+	// Each rule requires two instructions, which must fit the limit of 4096
+	// instructions of a filter.
+	if len(rules) > 2000 {
+		return nil, 0, errors.New("too many system call rules")
+	}
+
+	return rules, seccompAction(config.Profile.DefaultAction, config.Profile.DefaultErrnoRet), nil
+}
+
+// seccompAction returns the filter return value of a profile action.
+func seccompAction(action string, errnoRet *uint32) uint32 {
+	switch action {
+	case "SCMP_ACT_ALLOW":
+		return unix.SECCOMP_RET_ALLOW
+	case "SCMP_ACT_ERRNO":
+		errno := uint32(unix.EPERM)
+
+		if errnoRet != nil {
+			errno = *errnoRet
+		}
+
+		return unix.SECCOMP_RET_ERRNO | errno&unix.SECCOMP_RET_DATA
+	case "SCMP_ACT_KILL", "SCMP_ACT_KILL_THREAD":
+		return unix.SECCOMP_RET_KILL_THREAD
+	case "SCMP_ACT_LOG":
+		return unix.SECCOMP_RET_LOG
+	case "SCMP_ACT_TRAP":
+		return unix.SECCOMP_RET_TRAP
+	default:
+		return unix.SECCOMP_RET_KILL_PROCESS
+	}
+}
+
+// seccompFilter compiles the rules into a BPF program. Calls of other
+// architectures are denied, as their system call numbers differ.
+func seccompFilter(rules []seccompRule, defaultAction uint32) []unix.SockFilter {
+	load := func(offset uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset}
+	}
+
+	jumpIfEqual := func(value uint32, jumpTrue uint8, jumpFalse uint8) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: jumpTrue, Jf: jumpFalse, K: value}
+	}
+
+	ret := func(action uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: action}
+	}
+
+	filter := []unix.SockFilter{
+		load(seccompDataArch),
+		jumpIfEqual(seccompAuditArch, 1, 0),
+		ret(unix.SECCOMP_RET_KILL_PROCESS),
+		load(seccompDataNr),
+	}
+
+	if runtime.GOARCH == "amd64" {
+		filter = append(filter,
+			unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: 0, Jf: 1, K: seccompX32SyscallBit},
+			ret(unix.SECCOMP_RET_KILL_PROCESS),
+		)
+	}
+
+	for _, rule := range rules {
+		if rule.allowedArg == nil {
+			filter = append(filter,
+				jumpIfEqual(rule.syscall, 0, 1),
+				ret(rule.action),
+			)
+			continue
+		}
+
+		argOffset := uint32(seccompDataArgs + 8*rule.argIndex)
+
+		filter = append(filter,
+			jumpIfEqual(rule.syscall, 0, 6),
+			load(argOffset),
+			jumpIfEqual(uint32(*rule.allowedArg), 0, 2),
+			load(argOffset+4),
+			jumpIfEqual(uint32(*rule.allowedArg>>32), 1, 0),
+			ret(rule.action),
+			ret(unix.SECCOMP_RET_ALLOW),
+		)
+	}
+
+	return append(filter, ret(defaultAction))
+}
+
+// seccompViolation returns whether the process was terminated by a seccomp
+// filter, which sends SIGSYS.
+func seccompViolation(state *os.ProcessState) bool {
+	if state == nil {
+		return false
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+
+	return ok && status.Signaled() && status.Signal() == syscall.SIGSYS
+}
@@ -0,0 +1,180 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux && (amd64 || arm64)
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"golang.org/x/sys/unix"
+)
+
+func TestRunProgram_Seccomp(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "profile.json")
+
+	err := os.WriteFile(profile, []byte(`{
+		"defaultAction": "SCMP_ACT_ALLOW",
+		"syscalls": [
+			{"names": ["mkdir", "mkdirat"], "action": "SCMP_ACT_ERRNO", "errnoRet": 13},
+			{"names": ["not_a_syscall"], "action": "SCMP_ACT_KILL_PROCESS"}
+		]
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		profile       string
+		program       []string
+		expected      string
+		expectedError string
+	}{
+		"no-network-allowed": {
+			profile:  seccompProfileNoNetwork,
+			program:  []string{"/bin/sh", "-c", `echo '{}'`},
+			expected: "{}\n",
+		},
+		"no-network-denied": {
+			profile:       seccompProfileNoNetwork,
+			program:       []string{"/bin/bash", "-c", `exec 3<>/dev/tcp/127.0.0.1/9; echo '{}'`},
+			expectedError: "Seccomp Profile Violation",
+		},
+		"no-exec-allowed": {
+			profile:  seccompProfileNoExec,
+			program:  []string{"/bin/sh", "-c", `echo '{}'`},
+			expected: "{}\n",
+		},
+		"no-exec-denied": {
+			profile:       seccompProfileNoExec,
+			program:       []string{"/bin/sh", "-c", `exec /bin/true`},
+			expectedError: "Seccomp Profile Violation",
+		},
+		"no-exec-same-path-denied": {
+			profile:       seccompProfileNoExec,
+			program:       []string{"/bin/sh", "-c", `exec /bin/sh -c "echo '{}'"`},
+			expectedError: "Seccomp Profile Violation",
+		},
+		"no-exec-child-denied": {
+			profile:  seccompProfileNoExec,
+			program:  []string{"/bin/sh", "-c", `/bin/true; echo "{\"status\":\"$?\"}"`},
+			expected: "{\"status\":\"159\"}\n",
+		},
+		"file": {
+			profile:  profile,
+			program:  []string{"/bin/sh", "-c", `if mkdir "$1/created" 2>/dev/null; then echo '{"mkdir":"allowed"}'; else echo '{"mkdir":"denied"}'; fi`, "sh", t.TempDir()},
+			expected: "{\"mkdir\":\"denied\"}\n",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			seccomp, err := loadSeccompProfile(testCase.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n := NewExternalDataSource().(*externalDataSource)
+
+			invocation := programInvocation{
+				Program:       testCase.program,
+				AttributePath: path.Root("program"),
+				Sandbox:       &sandboxConfig{Seccomp: seccomp},
+			}
+
+			resultJson, _, diags := n.runProgram(context.Background(), invocation)
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() || diags.Errors()[0].Summary() != testCase.expectedError {
+					t.Fatalf("expected %q error, got: %v", testCase.expectedError, diags)
+				}
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if got := string(resultJson); got != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestMapExecPath(t *testing.T) {
+	t.Parallel()
+
+	first, err := mapExecPath("/bin/true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := mapExecPath("/bin/true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Errorf("expected random addresses, got %#x twice", first)
+	}
+
+	for _, addr := range []uintptr{first, second} {
+		if addr < seccompExecPathMinAddress || addr >= seccompExecPathMaxAddress {
+			t.Errorf("expected address within [%#x, %#x), got %#x", seccompExecPathMinAddress, seccompExecPathMaxAddress, addr)
+		}
+
+		page := unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(nil), addr)), os.Getpagesize())
+
+		if got := unix.ByteSliceToString(page); got != "/bin/true" {
+			t.Errorf("expected path %q, got %q", "/bin/true", got)
+		}
+
+		if _, _, errno := unix.RawSyscall(unix.SYS_MUNMAP, addr, uintptr(len(page)), 0); errno != 0 {
+			t.Error(errno)
+		}
+	}
+}
+
+func TestDataSource_SeccompProfile(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						seccomp_profile = "no-exec"
+						program         = ["/bin/sh", "-c", "exec /bin/true"]
+					}
+				`,
+				ExpectError: regexp.MustCompile(`(?s)Seccomp Profile Violation.*Seccomp Profile: no-exec`),
+			},
+		},
+	})
+}
+
+func TestDataSource_SeccompProfile_Invalid(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external" "test" {
+						seccomp_profile = "` + strings.ReplaceAll(filepath.Join(t.TempDir(), "missing.json"), `\`, `\\`) + `"
+						program         = ["/bin/sh", "-c", "echo '{}'"]
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Seccomp Profile`),
+			},
+		},
+	})
+}
@@ -0,0 +1,27 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !linux || !(amd64 || arm64)
+
+package provider
+
+import (
+	"errors"
+	"os"
+)
+
+// seccompSupported is whether seccomp profiles can be installed on this
+// platform.
+const seccompSupported = false
+
+func installSeccomp(config *seccompConfig, execPath uintptr) error {
+	return errors.New("seccomp profiles are only supported on Linux on amd64 and arm64")
+}
+
+func mapExecPath(program string) (uintptr, error) {
+	return 0, errors.New("seccomp profiles are only supported on Linux on amd64 and arm64")
+}
+
+func seccompViolation(state *os.ProcessState) bool {
+	return false
+}
@@ -0,0 +1,68 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSeccompProfile(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		profile       string
+		expectedError string
+	}{
+		"valid": {
+			profile: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mkdir", "mkdirat"], "action": "SCMP_ACT_ERRNO", "errnoRet": 13}]}`,
+		},
+		"empty-includes": {
+			profile: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mkdir"], "action": "SCMP_ACT_LOG", "includes": {}, "excludes": {}}]}`,
+		},
+		"missing-default-action": {
+			profile:       `{"syscalls": []}`,
+			expectedError: "missing defaultAction",
+		},
+		"unsupported-default-action": {
+			profile:       `{"defaultAction": "SCMP_ACT_NOTIFY"}`,
+			expectedError: `unsupported defaultAction "SCMP_ACT_NOTIFY"`,
+		},
+		"unsupported-action": {
+			profile:       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mkdir"], "action": "SCMP_ACT_TRACE"}]}`,
+			expectedError: `syscalls[0]: unsupported action "SCMP_ACT_TRACE"`,
+		},
+		"args": {
+			profile:       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["socket"], "action": "SCMP_ACT_ERRNO", "args": [{"index": 0, "value": 2, "op": "SCMP_CMP_EQ"}]}]}`,
+			expectedError: "syscalls[0]: argument conditions are not supported",
+		},
+		"includes": {
+			profile:       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mkdir"], "action": "SCMP_ACT_ERRNO", "includes": {"arches": ["amd64"]}}]}`,
+			expectedError: "syscalls[0]: includes and excludes are not supported",
+		},
+		"invalid-json": {
+			profile:       `{`,
+			expectedError: "unexpected EOF",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseSeccompProfile([]byte(testCase.profile))
+
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Fatalf("expected error containing %q, got: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
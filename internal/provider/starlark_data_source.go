@@ -0,0 +1,352 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	// starlarkFilename is the name of the script in Starlark error messages.
+	starlarkFilename = "script.star"
+
+	// starlarkEntrypoint is the function of the script called with the query.
+	starlarkEntrypoint = "main"
+
+	// starlarkMaxExecutionSteps bounds the computation of a script, so a
+	// script which never terminates fails instead of hanging Terraform.
+	starlarkMaxExecutionSteps = 100_000_000
+)
+
+var (
+	_ datasource.DataSource                   = (*starlarkDataSource)(nil)
+	_ datasource.DataSourceWithValidateConfig = (*starlarkDataSource)(nil)
+)
+
+func NewStarlarkDataSource() datasource.DataSource {
+	return &starlarkDataSource{}
+}
+
+type starlarkDataSource struct{}
+
+func (n *starlarkDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_starlark"
+}
+
+func (n *starlarkDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "The `external_starlark` data source evaluates a script written in Starlark, " +
+			"a dialect of Python, within the provider, without executing an external program.\n\n" +
+			"The script must define a `main` function, which is called with the query as a dict and must " +
+			"return a dict of string keys and string values. Scripts are hermetic: they have no access to the " +
+			"filesystem, the network or the environment, and cannot load other modules.",
+
+		Attributes: map[string]schema.Attribute{
+			"script": schema.StringAttribute{
+				Description: "The source code of the Starlark script, which must define a `main` function " +
+					"receiving the query.",
+				Required: true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"query": schema.MapAttribute{
+				Description: "A map of string values passed to the `main` function of the script as a dict. " +
+					"If not supplied, the function will receive an empty dict.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			"timeout": schema.StringAttribute{
+				Description: "The maximum duration of the evaluation of the script, such as `30s` or `5m`, " +
+					"after which it is cancelled. If not supplied, there is no time limit.",
+				Optional: true,
+			},
+
+			"result": schema.MapAttribute{
+				Description: "A map of string values returned from the `main` function of the script.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+
+			"id": schema.StringAttribute{
+				Description: "The id of the data source. This will always be set to `-`",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (n *starlarkDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config starlarkDataSourceModelV0
+
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.Timeout.IsNull() && !config.Timeout.IsUnknown() {
+		if _, err := parseTimeout(config.Timeout.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Invalid Timeout",
+				err.Error()+fmt.Sprintf("\n\nTimeout: %s", config.Timeout.ValueString()),
+			)
+		}
+	}
+
+	if config.Script.IsNull() || config.Script.IsUnknown() {
+		return
+	}
+
+	if _, err := starlarkFileOptions().Parse(starlarkFilename, config.Script.ValueString(), 0); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("script"),
+			"Invalid Starlark Script",
+			"The script could not be parsed. Verify the script is valid Starlark."+
+				fmt.Sprintf("\n\nError: %s", err),
+		)
+	}
+}
+
+func (n *starlarkDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config starlarkDataSourceModelV0
+
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var query map[string]types.String
+
+	diags = config.Query.ElementsAs(ctx, &query, false)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var timeout time.Duration
+
+	if !config.Timeout.IsNull() {
+		var err error
+
+		timeout, err = parseTimeout(config.Timeout.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("timeout"),
+				"Invalid Timeout",
+				err.Error()+fmt.Sprintf("\n\nTimeout: %s", config.Timeout.ValueString()),
+			)
+			return
+		}
+	}
+
+	result, diags := runStarlark(ctx, config.Script.ValueString(), filterQuery(query), timeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.Result, diags = types.MapValueFrom(ctx, types.StringType, result)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config.ID = types.StringValue("-")
+
+	diags = resp.State.Set(ctx, config)
+	resp.Diagnostics.Append(diags...)
+}
+
+type starlarkDataSourceModelV0 struct {
+	Script  types.String `tfsdk:"script"`
+	Query   types.Map    `tfsdk:"query"`
+	Timeout types.String `tfsdk:"timeout"`
+	Result  types.Map    `tfsdk:"result"`
+	ID      types.String `tfsdk:"id"`
+}
+
+// starlarkFileOptions returns the dialect of Starlark scripts, which is the
+// language as specified, without while loops, recursion or top-level
+// statements which would be allowed by extensions.
+func starlarkFileOptions() *syntax.FileOptions {
+	return &syntax.FileOptions{}
+}
+
+// runStarlark evaluates the script and returns the result of calling its main
+// function with the query.
+func runStarlark(ctx context.Context, script string, query map[string]string, timeout time.Duration) (map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	scriptPath := path.Root("script")
+
+	thread := &starlark.Thread{
+		Name: "external_starlark",
+		Print: func(_ *starlark.Thread, msg string) {
+			tflog.Debug(ctx, "Starlark script output", map[string]interface{}{"output": msg})
+		},
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, errors.New("loading modules is not supported")
+		},
+	}
+
+	thread.SetMaxExecutionSteps(starlarkMaxExecutionSteps)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Cancel the evaluation if the context is done, such as when Terraform
+	// is interrupted or the timeout is exceeded.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	predeclared := starlark.StringDict{
+		"json": starlarkjson.Module,
+	}
+
+	tflog.Trace(ctx, "Evaluating Starlark script", map[string]interface{}{"query": query})
+
+	globals, err := starlark.ExecFileOptions(starlarkFileOptions(), thread, starlarkFilename, script, predeclared)
+	if err != nil {
+		diags.Append(starlarkErrorDiagnostic(ctx, timeout, err))
+		return nil, diags
+	}
+
+	entrypoint, ok := globals[starlarkEntrypoint].(starlark.Callable)
+	if !ok {
+		diags.AddAttributeError(
+			scriptPath,
+			"Starlark Script Missing Main Function",
+			fmt.Sprintf("The script must define a function named %q, which receives the query as a dict ", starlarkEntrypoint)+
+				"and returns the result as a dict, for example:\n\n"+
+				"def main(query):\n"+
+				"    return {\"greeting\": \"Hello, \" + query[\"name\"]}",
+		)
+		return nil, diags
+	}
+
+	queryDict := starlark.NewDict(len(query))
+
+	for key, value := range query {
+		if err := queryDict.SetKey(starlark.String(key), starlark.String(value)); err != nil {
+			diags.AddAttributeError(
+				path.Root("query"),
+				"Query Handling Failed",
+				"The data source received an unexpected error while attempting to convert the query. "+
+					"This is always a bug in the external provider code and should be reported to the provider developers."+
+					fmt.Sprintf("\n\nError: %s", err),
+			)
+			return nil, diags
+		}
+	}
+
+	value, err := starlark.Call(thread, entrypoint, starlark.Tuple{queryDict}, nil)
+	if err != nil {
+		diags.Append(starlarkErrorDiagnostic(ctx, timeout, err))
+		return nil, diags
+	}
+
+	tflog.Trace(ctx, "Evaluated Starlark script", map[string]interface{}{"result": value.String()})
+
+	result, err := starlarkResult(value)
+	if err != nil {
+		diags.AddAttributeError(
+			scriptPath,
+			"Unexpected Starlark Script Results",
+			"The data source received unexpected results after evaluating the script.\n\n"+
+				"The main function must return a dict of string keys and string values."+
+				fmt.Sprintf("\n\nResult Error: %s", err),
+		)
+		return nil, diags
+	}
+
+	return result, diags
+}
+
+// starlarkResult converts the value returned by the main function of a script
+// into the result of the data source.
+func starlarkResult(value starlark.Value) (map[string]string, error) {
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want dict", value.Type())
+	}
+
+	result := make(map[string]string, dict.Len())
+
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("got key %s of type %s, want string", item[0], item[0].Type())
+		}
+
+		value, ok := starlark.AsString(item[1])
+		if !ok {
+			return nil, fmt.Errorf("got value %s of type %s for key %q, want string", item[1], item[1].Type(), key)
+		}
+
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// starlarkErrorDiagnostic returns the diagnostic of an error evaluating a
+// script, including the Starlark backtrace if available.
+func starlarkErrorDiagnostic(ctx context.Context, timeout time.Duration, err error) diag.Diagnostic {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return diag.NewAttributeErrorDiagnostic(
+			path.Root("timeout"),
+			"Starlark Script Timed Out",
+			"The script did not complete within the configured timeout and was cancelled. "+
+				"Increase the timeout or verify the script terminates."+
+				fmt.Sprintf("\n\nTimeout: %s", timeout),
+		)
+	}
+
+	message := err.Error()
+
+	var evalErr *starlark.EvalError
+
+	if errors.As(err, &evalErr) {
+		message = evalErr.Backtrace()
+	}
+
+	return diag.NewAttributeErrorDiagnostic(
+		path.Root("script"),
+		"Starlark Script Evaluation Failed",
+		"The data source received an unexpected error while attempting to evaluate the script."+
+			fmt.Sprintf("\n\nError Message: %s", message),
+	)
+}
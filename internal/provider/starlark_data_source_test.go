@@ -0,0 +1,163 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestDataSource_Starlark(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external_starlark" "test" {
+						script = <<-EOT
+							def main(query):
+							    return {"greeting": "Hello, " + query["name"]}
+						EOT
+
+						query = {
+							name = "pizza"
+						}
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.external_starlark.test", "result.greeting", "Hello, pizza"),
+					resource.TestCheckResourceAttr("data.external_starlark.test", "id", "-"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSource_Starlark_InvalidScript(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV5ProviderFactories: protoV5ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					data "external_starlark" "test" {
+						script = "def main(query)"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`Invalid Starlark Script`),
+			},
+		},
+	})
+}
+
+func TestRunStarlark(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		script        string
+		query         map[string]string
+		timeout       time.Duration
+		expected      map[string]string
+		expectedError string
+	}{
+		"query": {
+			script: `
+def main(query):
+    return {k.upper(): v for k, v in query.items()}
+`,
+			query:    map[string]string{"a": "1", "b": "2"},
+			expected: map[string]string{"A": "1", "B": "2"},
+		},
+		"json": {
+			script: `
+def main(query):
+    doc = json.decode(query["doc"])
+    return {"names": ",".join([item["name"] for item in doc])}
+`,
+			query:    map[string]string{"doc": `[{"name": "a"}, {"name": "b"}]`},
+			expected: map[string]string{"names": "a,b"},
+		},
+		"empty-query": {
+			script: `
+def main(query):
+    return {"count": str(len(query))}
+`,
+			expected: map[string]string{"count": "0"},
+		},
+		"missing-main": {
+			script:        `x = 1`,
+			expectedError: "Starlark Script Missing Main Function",
+		},
+		"fail": {
+			script: `
+def main(query):
+    fail("I was asked to fail")
+`,
+			expectedError: "I was asked to fail",
+		},
+		"load": {
+			script:        `load("other.star", "x")`,
+			expectedError: "loading modules is not supported",
+		},
+		"non-dict-result": {
+			script: `
+def main(query):
+    return ["a"]
+`,
+			expectedError: "got list, want dict",
+		},
+		"non-string-value": {
+			script: `
+def main(query):
+    return {"a": 1}
+`,
+			expectedError: `got value 1 of type int for key "a", want string`,
+		},
+		"timeout": {
+			script: `
+def main(query):
+    for i in range(1000000000):
+        pass
+`,
+			timeout:       10 * time.Millisecond,
+			expectedError: "Starlark Script Timed Out",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, diags := runStarlark(context.Background(), testCase.script, testCase.query, testCase.timeout)
+
+			if testCase.expectedError != "" {
+				if !diags.HasError() {
+					t.Fatal("expected error, got none")
+				}
+
+				gotError := fmt.Sprintf("%s: %s", diags.Errors()[0].Summary(), diags.Errors()[0].Detail())
+
+				if !strings.Contains(gotError, testCase.expectedError) {
+					t.Fatalf("expected error containing %q, got: %s", testCase.expectedError, gotError)
+				}
+
+				return
+			}
+
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+
+			if diff := cmp.Diff(testCase.expected, got); diff != "" {
+				t.Errorf("unexpected difference: %s", diff)
+			}
+		})
+	}
+}
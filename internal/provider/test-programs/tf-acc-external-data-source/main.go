@@ -4,59 +4,102 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-external/externalprogram"
 )
 
 // This is a minimal implementation of the external data source protocol
-// intended only for use in the provider acceptance tests.
+// intended only for use in the provider acceptance tests, which also
+// exercises the externalprogram package.
 //
 // In practice it's likely not much harder to just write a real Terraform
 // plugin if you're going to be writing your data source in Go anyway;
 // this example is just in Go because we want to avoid introducing
 // additional language runtimes into the test environment.
 func main() {
-	queryBytes, err := io.ReadAll(os.Stdin)
-	if err != nil {
-		panic(err)
+	if len(os.Args) >= 2 && os.Args[1] == "--persistent" {
+		externalprogram.RunPersistent(&persistentProgram{})
+		return
 	}
 
-	var query map[string]*string
-	err = json.Unmarshal(queryBytes, &query)
-	if err != nil {
-		panic(err)
+	// A worker which never reads its standard input.
+	if len(os.Args) >= 2 && os.Args[1] == "--stalled" {
+		time.Sleep(time.Hour)
+		return
 	}
 
-	if _, ok := query["fail"]; ok {
-		fmt.Fprintf(os.Stderr, "I was asked to fail\n")
-		os.Exit(1)
+	externalprogram.Run(externalprogram.ProgramFunc(read))
+}
+
+func read(ctx context.Context, query externalprogram.Query) (externalprogram.Result, error) {
+	if _, ok := query.Lookup("fail"); ok {
+		return nil, errors.New("I was asked to fail")
 	}
 
-	var result = map[string]string{
+	result := externalprogram.Result{
 		"result": "yes",
 	}
 
-	if queryValue, ok := query["value"]; ok && queryValue != nil {
-		result["query_value"] = *queryValue
+	if queryValue, ok := query.Lookup("value"); ok {
+		result.Set("query_value", queryValue)
 	}
 
 	if len(os.Args) >= 2 {
-		result["argument"] = os.Args[1]
+		result.Set("argument", os.Args[1])
 	}
 
 	for queryKey, queryValue := range query {
-		if queryValue != nil {
-			result[queryKey] = *queryValue
+		result.Set(queryKey, queryValue)
+	}
+
+	return result, nil
+}
+
+// persistentProgram implements the persistent program protocol, answering
+// every request until the standard input is closed.
+type persistentProgram struct {
+	requests int64
+}
+
+func (p *persistentProgram) Read(ctx context.Context, query externalprogram.Query) (externalprogram.Result, error) {
+	p.requests++
+
+	// Crash once, using the file as a marker for the restarted program.
+	if marker, ok := query.Lookup("crash"); ok {
+		if _, err := os.Stat(marker); os.IsNotExist(err) {
+			_ = os.WriteFile(marker, nil, 0o600)
+			fmt.Fprintf(os.Stderr, "I was asked to crash\n")
+			os.Exit(1)
 		}
 	}
 
-	resultBytes, err := json.Marshal(result)
-	if err != nil {
-		panic(err)
+	if _, ok := query.Lookup("sleep"); ok {
+		time.Sleep(time.Second)
+	}
+
+	if _, ok := query.Lookup("fail"); ok {
+		return nil, errors.New("I was asked to fail")
+	}
+
+	if _, err := query.Int("count", 0); err != nil {
+		return nil, err
+	}
+
+	result := externalprogram.Result{
+		"pid": strconv.Itoa(os.Getpid()),
+	}
+
+	result.SetInt("requests", p.requests)
+
+	for queryKey, queryValue := range query {
+		result.Set(queryKey, queryValue)
 	}
 
-	os.Stdout.Write(resultBytes)
-	os.Exit(0)
+	return result, nil
 }
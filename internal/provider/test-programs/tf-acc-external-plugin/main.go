@@ -0,0 +1,82 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-external/externalplugin"
+)
+
+// This is a minimal helper served with the externalplugin package intended
+// only for use in the provider acceptance tests.
+func main() {
+	// A helper which never completes the handshake.
+	if len(os.Args) >= 2 && os.Args[1] == "--hang" {
+		time.Sleep(time.Hour)
+		return
+	}
+
+	externalplugin.Serve(&helper{})
+}
+
+type helper struct {
+	reads atomic.Int64
+}
+
+func (h *helper) Read(ctx context.Context, query map[string]string) (map[string]string, error) {
+	if _, ok := query["fail"]; ok {
+		return nil, errors.New("I was asked to fail")
+	}
+
+	if _, ok := query["sleep"]; ok {
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	reads := h.reads.Add(1)
+
+	result := map[string]string{
+		"pid":   strconv.Itoa(os.Getpid()),
+		"reads": strconv.FormatInt(reads, 10),
+		"value": query["value"],
+	}
+
+	if name, ok := query["env"]; ok {
+		result["env"] = os.Getenv(name)
+	}
+
+	return result, nil
+}
+
+func (h *helper) Validate(_ context.Context, query map[string]string) error {
+	if query["value"] == "invalid" {
+		return &externalplugin.Error{
+			Summary:  "Invalid Value",
+			Detail:   "I was asked to reject the value.",
+			QueryKey: "value",
+		}
+	}
+
+	return nil
+}
+
+func (h *helper) Schema(_ context.Context) (*externalplugin.Schema, error) {
+	return &externalplugin.Schema{
+		Query: map[string]externalplugin.QueryKey{
+			"value": {Description: "The value to return.", Required: true},
+			"fail":  {},
+			"sleep": {},
+			"env":   {Description: "The name of an environment variable to return."},
+		},
+	}, nil
+}
@@ -0,0 +1,588 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// workerShutdownTimeout is how long a worker may take to exit after its
+	// standard input is closed before it is killed.
+	workerShutdownTimeout = 5 * time.Second
+
+	// workerStderrLimit is the number of trailing bytes of the standard
+	// error of a worker included in diagnostics.
+	workerStderrLimit = 4096
+)
+
+var (
+	// workerPools contains the worker pools of all configured providers, so
+	// their workers can be stopped when the provider server stops.
+	workerPools   []*workerPool
+	workerPoolsMu sync.Mutex
+)
+
+// StopWorkers stops the persistent workers and the plugins of all providers.
+// It is called when the provider server stops.
+func StopWorkers() {
+	workerPoolsMu.Lock()
+	pools := workerPools
+	workerPools = nil
+	workerPoolsMu.Unlock()
+
+	for _, pool := range pools {
+		pool.Close()
+	}
+
+	plugin.CleanupClients()
+}
+
+// workerPool manages the persistent workers of a provider, with one worker
+// per distinct program, working directory and environment.
+type workerPool struct {
+	mu      sync.Mutex
+	closed  bool
+	workers map[string]*workerSlot
+}
+
+// workerSlot serializes the requests sent to a single worker and holds the
+// worker while it is running.
+type workerSlot struct {
+	mu     sync.Mutex
+	worker *worker
+}
+
+// worker is a running program process exchanging newline-delimited JSON-RPC
+// 2.0 messages over its standard input and output.
+type worker struct {
+	program string
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	stderr  *tailBuffer
+	kill    func() error
+	nextID  int64
+
+	// exited is closed once the process has exited, after which waitErr
+	// contains the result of waiting for the process.
+	exited  chan struct{}
+	waitErr error
+}
+
+type workerRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      int64             `json:"id"`
+	Method  string            `json:"method"`
+	Params  map[string]string `json:"params"`
+}
+
+type workerResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *workerError    `json:"error"`
+}
+
+type workerError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *workerError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// workerErrorData is the data of errors returned by programs built with the
+// externalprogram package, which carry their own summary and may relate to a
+// query key.
+type workerErrorData struct {
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	QueryKey string `json:"query_key"`
+}
+
+// diagnostic returns the diagnostic of an error returned by a persistent
+// program. Errors with a summary in their data are reported with their own
+// summary and detail, on the query key they relate to.
+func (e *workerError) diagnostic(attributePath path.Path, programPath string) diag.Diagnostic {
+	var data workerErrorData
+
+	if len(e.Data) == 0 || json.Unmarshal(e.Data, &data) != nil || data.Summary == "" {
+		return diag.NewAttributeErrorDiagnostic(
+			attributePath,
+			"External Program Execution Failed",
+			"The persistent program returned an error in response to the query."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nError Message: %s", e.Message)+
+				fmt.Sprintf("\nState: JSON-RPC error code %d", e.Code),
+		)
+	}
+
+	if data.QueryKey != "" {
+		attributePath = path.Root("query").AtMapKey(data.QueryKey)
+	}
+
+	return diag.NewAttributeErrorDiagnostic(
+		attributePath,
+		data.Summary,
+		data.Detail+
+			fmt.Sprintf("\n\nProgram: %s", programPath)+
+			fmt.Sprintf("\nState: JSON-RPC error code %d", e.Code),
+	)
+}
+
+// errWorkerTransport is wrapped by errors caused by a worker which crashed
+// or violated the protocol, after which the worker is restarted.
+var errWorkerTransport = errors.New("worker transport failed")
+
+func newWorkerPool() *workerPool {
+	pool := &workerPool{
+		workers: make(map[string]*workerSlot),
+	}
+
+	workerPoolsMu.Lock()
+	workerPools = append(workerPools, pool)
+	workerPoolsMu.Unlock()
+
+	return pool
+}
+
+// Close stops all workers of the pool. Workers are asked to exit by closing
+// their standard input and are killed if they do not exit in time.
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	slots := p.workers
+	p.workers = make(map[string]*workerSlot)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for _, slot := range slots {
+		wg.Add(1)
+
+		go func(slot *workerSlot) {
+			defer wg.Done()
+
+			slot.mu.Lock()
+			defer slot.mu.Unlock()
+
+			if slot.worker != nil {
+				slot.worker.stop()
+				slot.worker = nil
+			}
+		}(slot)
+	}
+
+	wg.Wait()
+}
+
+// slot returns the slot of the worker for the invocation.
+func (p *workerPool) slot(invocation programInvocation) (*workerSlot, error) {
+	key := workerKey(invocation)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, errors.New("the provider is stopping")
+	}
+
+	slot, ok := p.workers[key]
+
+	if !ok {
+		slot = &workerSlot{}
+		p.workers[key] = slot
+	}
+
+	return slot, nil
+}
+
+// workerKey identifies the worker of an invocation.
+func workerKey(invocation programInvocation) string {
+	environment := make([]string, 0, len(invocation.Environment))
+
+	for key, value := range invocation.Environment {
+		environment = append(environment, key+"="+value)
+	}
+
+	sort.Strings(environment)
+
+	parts := []string{strings.Join(invocation.Program, "\x00"), invocation.WorkingDir, strings.Join(environment, "\x00"), invocation.ProgramSHA256, invocation.ProgramSignature}
+
+	if invocation.Credential != nil {
+		parts = append(parts, fmt.Sprintf("%d:%d", invocation.Credential.Uid, invocation.Credential.Gid))
+	}
+
+	if invocation.Sandbox != nil {
+		sandbox, _ := json.Marshal(invocation.Sandbox)
+		parts = append(parts, string(sandbox))
+	}
+
+	return strings.Join(parts, "\x01")
+}
+
+// call sends the query to the worker of the invocation, starting the worker
+// if it is not running, and returns the result along with the number of bytes
+// the worker wrote to its standard error while answering. If the worker
+// crashed or violated the protocol, it is restarted once and the query is
+// sent again.
+func (p *workerPool) call(ctx context.Context, invocation programInvocation) (json.RawMessage, *worker, int, error) {
+	slot, err := p.slot(invocation)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	var result json.RawMessage
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if slot.worker == nil || slot.worker.hasExited() {
+			if slot.worker != nil {
+				tflog.Debug(ctx, "Restarting exited persistent external program", map[string]interface{}{"program": slot.worker.program, "error": slot.worker.waitErr})
+			}
+
+			slot.worker, err = startWorker(invocation)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+		}
+
+		w := slot.worker
+
+		stderrBefore := w.stderr.Written()
+
+		result, err = w.call(ctx, invocation.Query)
+
+		stderrBytes := w.stderr.Written() - stderrBefore
+
+		if err == nil || !errors.Is(err, errWorkerTransport) || ctx.Err() != nil {
+			if ctx.Err() != nil {
+				// The state of the worker is unknown after an abandoned
+				// request, so it is never reused.
+				w.stop()
+				slot.worker = nil
+			}
+
+			return result, w, stderrBytes, err
+		}
+
+		w.stop()
+		slot.worker = nil
+	}
+
+	return nil, nil, 0, err
+}
+
+// startWorker starts the program of the invocation as a worker. The worker
+// is not bound to the context of a single read.
+func startWorker(invocation programInvocation) (*worker, error) {
+	cmd := invocation.command(context.Background())
+	programPath := cmd.Path
+
+	verified, err := invocation.verifyProgram(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	applySandbox(cmd, verified.sandbox(invocation.Sandbox))
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		_ = verified.Close()
+		return nil, err
+	}
+
+	// An explicit pipe is used for the standard output, as the pipe created
+	// by StdoutPipe is closed by Wait, possibly before the last response was
+	// read.
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		_ = verified.Close()
+		return nil, err
+	}
+
+	cmd.Stdout = stdoutWriter
+
+	// Do not wait indefinitely for the standard error of processes started
+	// by the worker which outlive it.
+	cmd.WaitDelay = time.Second
+
+	stderr := &tailBuffer{limit: workerStderrLimit}
+	cmd.Stderr = stderr
+
+	err = cmd.Start()
+
+	// The write end of the pipe is now owned by the process.
+	_ = stdoutWriter.Close()
+
+	if err != nil {
+		_ = stdoutReader.Close()
+		_ = verified.Close()
+		return nil, err
+	}
+
+	w := &worker{
+		program: programPath,
+		stdin:   stdin,
+		stdout:  bufio.NewReader(stdoutReader),
+		stderr:  stderr,
+		kill:    cmd.Process.Kill,
+		exited:  make(chan struct{}),
+	}
+
+	go func() {
+		w.waitErr = cmd.Wait()
+		_ = stdoutReader.Close()
+		// The copy of the verified program is removed once it exited.
+		_ = verified.Close()
+		close(w.exited)
+	}()
+
+	return w, nil
+}
+
+func (w *worker) hasExited() bool {
+	select {
+	case <-w.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes the standard input of the worker, which asks it to exit, and
+// kills it if it has not exited within workerShutdownTimeout.
+func (w *worker) stop() {
+	_ = w.stdin.Close()
+
+	select {
+	case <-w.exited:
+	case <-time.After(workerShutdownTimeout):
+		_ = w.kill()
+		<-w.exited
+	}
+}
+
+// call sends a single read request to the worker and waits for its response.
+func (w *worker) call(ctx context.Context, query map[string]string) (json.RawMessage, error) {
+	w.nextID++
+
+	request, err := json.Marshal(workerRequest{
+		JSONRPC: "2.0",
+		ID:      w.nextID,
+		Method:  "read",
+		Params:  query,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type callResult struct {
+		line []byte
+		err  error
+	}
+
+	results := make(chan callResult, 1)
+
+	// The request is written in the same goroutine the response is read
+	// from, as writing blocks once the pipe is full if the worker stopped
+	// reading its standard input.
+	go func() {
+		if _, err := w.stdin.Write(append(request, '\n')); err != nil {
+			results <- callResult{err: fmt.Errorf("%w: unable to write request: %s", errWorkerTransport, err)}
+			return
+		}
+
+		line, err := w.stdout.ReadBytes('\n')
+		if err != nil {
+			err = fmt.Errorf("%w: unable to read response: %s", errWorkerTransport, err)
+		}
+
+		results <- callResult{line: line, err: err}
+	}()
+
+	var line callResult
+
+	select {
+	case <-ctx.Done():
+		// Killing the worker unblocks the pending write or read, as the
+		// pipes are closed once it exited.
+		_ = w.kill()
+		<-results
+
+		return nil, ctx.Err()
+	case line = <-results:
+	}
+
+	if line.err != nil {
+		return nil, line.err
+	}
+
+	var response workerResponse
+
+	if err := json.Unmarshal(line.line, &response); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON-RPC response %q: %s", errWorkerTransport, line.line, err)
+	}
+
+	if string(response.ID) != fmt.Sprint(w.nextID) {
+		return nil, fmt.Errorf("%w: response id %s does not match request id %d", errWorkerTransport, response.ID, w.nextID)
+	}
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	return response.Result, nil
+}
+
+// tailBuffer is a concurrency-safe writer retaining only the last bytes
+// written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+
+	// written is the number of bytes ever written.
+	written int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	b.written += len(p)
+
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.data)
+}
+
+// Written returns the number of bytes ever written, including those no
+// longer retained.
+func (b *tailBuffer) Written() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.written
+}
+
+// runWorker sends the query of the invocation to a persistent worker running
+// its program and returns the result along with the resolved path of the
+// program.
+func (n *externalDataSource) runWorker(ctx context.Context, invocation programInvocation) ([]byte, string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	diags.Append(n.lookupProgram(invocation)...)
+	if diags.HasError() {
+		return nil, "", diags
+	}
+
+	if invocation.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, invocation.Timeout)
+		defer cancel()
+	}
+
+	tflog.Trace(ctx, "Sending query to persistent external program", map[string]interface{}{"program": invocation.Program})
+
+	start := time.Now()
+
+	workers := n.providerData.Workers
+
+	// Without a configured provider, the worker only lives for this read.
+	if workers == nil {
+		workers = &workerPool{workers: make(map[string]*workerSlot)}
+		defer workers.Close()
+	}
+
+	result, w, stderrBytes, err := workers.call(ctx, invocation)
+
+	programPath := invocation.Program[0]
+	var stderr string
+
+	if w != nil {
+		programPath = w.program
+		stderr = w.stderr.String()
+	}
+
+	tflog.Trace(ctx, "Received response from persistent external program", map[string]interface{}{"program": programPath, "output": string(result), "stderr": stderr})
+
+	if n.providerData.AuditLog != nil {
+		exitStatus := 0
+
+		if err != nil {
+			exitStatus = -1
+		}
+
+		record := newAuditRecord(start, programPath, invocation.Program[1:], invocation.WorkingDir, invocation.Query, exitStatus, result, stderrBytes)
+
+		diags.Append(n.writeAuditRecord(invocation, record, fmt.Sprintf("Program: %s", programPath))...)
+		if diags.HasError() {
+			return nil, programPath, diags
+		}
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			diags.Append(invocation.timeoutDiagnostic(fmt.Sprintf("Program: %s", programPath), err))
+			return nil, programPath, diags
+		}
+
+		if digestDiag, ok := invocation.programVerificationDiagnostic(programPath, err); ok {
+			diags.Append(digestDiag)
+			return nil, programPath, diags
+		}
+
+		var rpcErr *workerError
+
+		if errors.As(err, &rpcErr) {
+			diags.Append(rpcErr.diagnostic(invocation.AttributePath, programPath))
+			return nil, programPath, diags
+		}
+
+		diags.AddAttributeError(
+			invocation.AttributePath,
+			"External Program Execution Failed",
+			"The data source received an unexpected error while attempting to exchange the query with the persistent program. "+
+				"Persistent programs must read newline-delimited JSON-RPC 2.0 requests from standard input and write one "+
+				"response per line to standard output."+
+				fmt.Sprintf("\n\nProgram: %s", programPath)+
+				fmt.Sprintf("\nError Message: %s", stderr)+
+				fmt.Sprintf("\nState: %s", err),
+		)
+		return nil, programPath, diags
+	}
+
+	return result, programPath, diags
+}
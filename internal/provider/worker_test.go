@@ -0,0 +1,151 @@
+// Copyright IBM Corp. 2017, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+func TestTailBuffer(t *testing.T) {
+	t.Parallel()
+
+	b := &tailBuffer{limit: 4}
+
+	for _, s := range []string{"abc", "def"} {
+		if _, err := b.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := b.String(); got != "cdef" {
+		t.Errorf("expected %q to be retained, got %q", "cdef", got)
+	}
+
+	if got := b.Written(); got != 6 {
+		t.Errorf("expected 6 bytes written, got %d", got)
+	}
+}
+
+func TestRunWorker(t *testing.T) {
+	t.Parallel()
+
+	programPath, err := buildDataSourceTestProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewExternalDataSource().(*externalDataSource)
+	n.providerData.Workers = &workerPool{workers: make(map[string]*workerSlot)}
+	t.Cleanup(n.providerData.Workers.Close)
+
+	read := func(query map[string]string, timeout time.Duration) (map[string]string, error) {
+		invocation := programInvocation{
+			Program:       []string{programPath, "--persistent"},
+			Query:         query,
+			Timeout:       timeout,
+			TimeoutPath:   path.Root("timeout"),
+			AttributePath: path.Root("program"),
+		}
+
+		resultJson, _, diags := n.runWorker(context.Background(), invocation)
+
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s: %s", diags.Errors()[0].Summary(), diags.Errors()[0].Detail())
+		}
+
+		var result map[string]string
+
+		if err := json.Unmarshal(resultJson, &result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	first, err := read(map[string]string{"value": "pizza"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if first["value"] != "pizza" {
+		t.Errorf("unexpected result: %v", first)
+	}
+
+	second, err := read(map[string]string{"value": "pasta"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if diff := cmp.Diff(map[string]string{"pid": first["pid"], "requests": "2", "value": "pasta"}, second); diff != "" {
+		t.Errorf("expected worker to be reused: %s", diff)
+	}
+
+	_, err = read(map[string]string{"fail": "true"}, 0)
+	if err == nil || !strings.Contains(err.Error(), "I was asked to fail") {
+		t.Errorf("expected JSON-RPC error, got: %v", err)
+	}
+
+	_, _, diags := n.runWorker(context.Background(), programInvocation{
+		Program:       []string{programPath, "--persistent"},
+		Query:         map[string]string{"count": "many"},
+		AttributePath: path.Root("program"),
+	})
+
+	if len(diags) != 1 || diags[0].Summary() != "Invalid Query Value" {
+		t.Errorf("expected diagnostic of the program error, got: %v", diags)
+	} else if diagWithPath, ok := diags[0].(diag.DiagnosticWithPath); !ok || !diagWithPath.Path().Equal(path.Root("query").AtMapKey("count")) {
+		t.Errorf("expected diagnostic on the query key, got: %v", diags[0])
+	}
+
+	crashed, err := read(map[string]string{"crash": filepath.Join(t.TempDir(), "crashed")}, 0)
+	if err != nil {
+		t.Fatalf("expected crashed worker to be restarted, got: %s", err)
+	}
+
+	if crashed["pid"] == first["pid"] || crashed["requests"] != "1" {
+		t.Errorf("expected a new worker, got: %v", crashed)
+	}
+
+	_, err = read(map[string]string{"sleep": "true"}, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "External Program Timed Out") {
+		t.Errorf("expected timeout, got: %v", err)
+	}
+
+	stalled := programInvocation{
+		Program:       []string{programPath, "--stalled"},
+		Query:         map[string]string{"value": strings.Repeat("x", 1<<20)},
+		Timeout:       100 * time.Millisecond,
+		TimeoutPath:   path.Root("timeout"),
+		AttributePath: path.Root("program"),
+	}
+
+	start := time.Now()
+
+	if _, _, diags := n.runWorker(context.Background(), stalled); !diags.HasError() || diags.Errors()[0].Summary() != "External Program Timed Out" {
+		t.Errorf("expected timeout of a worker not reading the query, got: %v", diags)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the timeout to apply while writing the query, took %s", elapsed)
+	}
+
+	afterTimeout, err := read(nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if afterTimeout["pid"] == crashed["pid"] {
+		t.Errorf("expected worker to be replaced after timeout, got: %v", afterTimeout)
+	}
+}
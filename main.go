@@ -14,6 +14,9 @@ import (
 )
 
 func main() {
+	// The provider re-executes itself to set up the sandbox of programs.
+	provider.RunSandboxShim()
+
 	var debug bool
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
@@ -24,6 +27,9 @@ func main() {
 		Debug:           debug,
 		ProtocolVersion: 5,
 	})
+
+	provider.StopWorkers()
+
 	if err != nil {
 		log.Fatal(err)
 	}